@@ -1,36 +1,217 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"os"
+	"strings"
 
 	enclave2 "github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/base/op-enclave/op-enclave/nsmsim"
+	opservice "github.com/ethereum-optimism/optimism/op-service"
+	"github.com/ethereum-optimism/optimism/op-service/ctxinterrupt"
 	oplog "github.com/ethereum-optimism/optimism/op-service/log"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/mdlayher/vsock"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	Version   = "v0.0.1"
+	GitCommit = ""
+	GitDate   = ""
+)
+
+const EnvVarPrefix = "OP_ENCLAVE"
+
+func prefixEnvVars(name string) []string {
+	return opservice.PrefixEnvVar(EnvVarPrefix, name)
+}
+
+var (
+	VsockCIDFlag = &cli.UintFlag{
+		Name:    "vsock-cid",
+		Usage:   "vsock context ID to listen on (default: the enclave's own context ID)",
+		EnvVars: prefixEnvVars("VSOCK_CID"),
+	}
+	VsockPortFlag = &cli.UintFlag{
+		Name:    "vsock-port",
+		Usage:   "vsock port to listen on",
+		EnvVars: prefixEnvVars("VSOCK_PORT"),
+		Value:   1234,
+	}
+	HTTPAddrFlag = &cli.StringFlag{
+		Name:    "http-addr",
+		Usage:   "HTTP address to listen on when vsock is unavailable (outside of an enclave)",
+		EnvVars: prefixEnvVars("HTTP_ADDR"),
+		Value:   ":1234",
+	}
+	TrustedPCR0sFlag = &cli.StringSliceFlag{
+		Name:    "trusted-pcr0s",
+		Usage:   "PCR0s of other enclave images that signer keys can be transferred to and from, comma-separated",
+		EnvVars: prefixEnvVars("TRUSTED_PCR0S"),
+	}
+	ConfigSignerFlag = &cli.StringFlag{
+		Name:    "config-signer",
+		Usage:   "Address that signs the trusted PCR0s passed to enclave_setTrustedPCR0s",
+		EnvVars: prefixEnvVars("CONFIG_SIGNER"),
+	}
+	AttestationRootsFlag = &cli.StringFlag{
+		Name:    "attestation-roots",
+		Usage:   "PEM file of root certificates to verify other enclaves' attestations with (default: AWS Nitro root)",
+		EnvVars: prefixEnvVars("ATTESTATION_ROOTS"),
+	}
+	NSMSimulatorCAFlag = &cli.StringFlag{
+		Name:    "nsm-simulator-ca",
+		Usage:   "Use a simulated Nitro Secure Module whose attestations are signed by the CA in this PEM file (insecure, for testing)",
+		EnvVars: prefixEnvVars("NSM_SIMULATOR_CA"),
+	}
+	NSMSimulatorPCR0Flag = &cli.StringFlag{
+		Name:    "nsm-simulator-pcr0",
+		Usage:   "PCR0 reported by the simulated Nitro Secure Module",
+		EnvVars: prefixEnvVars("NSM_SIMULATOR_PCR0"),
+	}
 )
 
 func main() {
 	oplog.SetupDefaults()
 
-	s := rpc.NewServer()
-	serv, err := enclave2.NewServer()
+	app := cli.NewApp()
+	app.Flags = append([]cli.Flag{
+		VsockCIDFlag,
+		VsockPortFlag,
+		HTTPAddrFlag,
+		TrustedPCR0sFlag,
+		ConfigSignerFlag,
+		AttestationRootsFlag,
+		NSMSimulatorCAFlag,
+		NSMSimulatorPCR0Flag,
+	}, oplog.CLIFlags(EnvVarPrefix)...)
+	app.Version = opservice.FormatVersion(Version, GitCommit, GitDate, "")
+	app.Name = "op-enclave"
+	app.Usage = "Enclave RPC server"
+	app.Action = Main
+
+	ctx := ctxinterrupt.WithSignalWaiterMain(context.Background())
+	err := app.RunContext(ctx, os.Args)
+	if err != nil {
+		log.Crit("Application failed", "message", err)
+	}
+}
+
+func Main(cliCtx *cli.Context) error {
+	logger := oplog.NewLogger(oplog.AppOut(cliCtx), oplog.ReadCLIConfig(cliCtx))
+	oplog.SetGlobalLogHandler(logger.Handler())
+
+	opts, err := serverOptions(cliCtx)
 	if err != nil {
-		log.Crit("Error creating API server", "error", err)
+		return err
 	}
-	err = s.RegisterName(enclave2.Namespace, serv)
+	serv, err := enclave2.NewServer(opts...)
 	if err != nil {
-		log.Crit("Error registering API", "error", err)
+		return fmt.Errorf("error creating API server: %w", err)
+	}
+	s := rpc.NewServer()
+	if err = s.RegisterName(enclave2.Namespace, serv); err != nil {
+		return fmt.Errorf("error registering API: %w", err)
 	}
 
-	listener, err := vsock.Listen(1234, &vsock.Config{})
+	var listener net.Listener
+	cid, port := uint32(cliCtx.Uint(VsockCIDFlag.Name)), uint32(cliCtx.Uint(VsockPortFlag.Name))
+	if cliCtx.IsSet(VsockCIDFlag.Name) {
+		listener, err = vsock.ListenContextID(cid, port, &vsock.Config{})
+	} else {
+		listener, err = vsock.Listen(port, &vsock.Config{})
+	}
 	if err != nil {
-		log.Warn("Error opening vsock listener, running in HTTP mode", "error", err)
-		err = http.ListenAndServe(":1234", s)
+		addr := cliCtx.String(HTTPAddrFlag.Name)
+		logger.Warn("Error opening vsock listener, running in HTTP mode", "error", err, "addr", addr)
+		err = http.ListenAndServe(addr, s)
 	} else {
+		logger.Info("Listening on vsock", "addr", listener.Addr())
 		err = s.ServeListener(listener)
 	}
+	if err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error starting server: %w", err)
+	}
+	return nil
+}
+
+func serverOptions(cliCtx *cli.Context) ([]enclave2.Option, error) {
+	opts := []enclave2.Option{
+		enclave2.WithVersion(cliCtx.App.Version),
+	}
+	if trusted := cliCtx.StringSlice(TrustedPCR0sFlag.Name); len(trusted) > 0 {
+		var pcr0s [][]byte
+		for _, pcr0 := range trusted {
+			decoded, err := hexutil.Decode(strings.TrimSpace(pcr0))
+			if err != nil {
+				return nil, fmt.Errorf("error parsing trusted PCR0 %s: %w", pcr0, err)
+			}
+			pcr0s = append(pcr0s, decoded)
+		}
+		opts = append(opts, enclave2.WithTrustedPCR0s(pcr0s))
+	}
+	if signer := cliCtx.String(ConfigSignerFlag.Name); signer != "" {
+		if !common.IsHexAddress(signer) {
+			return nil, fmt.Errorf("invalid config signer address: %s", signer)
+		}
+		opts = append(opts, enclave2.WithConfigSigner(common.HexToAddress(signer)))
+	}
+	if rootsFile := cliCtx.String(AttestationRootsFlag.Name); rootsFile != "" {
+		roots, err := enclave2.LoadRoots(rootsFile)
+		if err != nil {
+			return nil, fmt.Errorf("error loading attestation roots: %w", err)
+		}
+		opts = append(opts, enclave2.WithAttestationRoots(roots))
+	}
+	if caFile := cliCtx.String(NSMSimulatorCAFlag.Name); caFile != "" {
+		simOpts, err := simulatorOptions(caFile, cliCtx.String(NSMSimulatorPCR0Flag.Name))
+		if err != nil {
+			return nil, err
+		}
+		opts = append(opts, simOpts...)
+	}
+	return opts, nil
+}
+
+// simulatorOptions replaces the NSM device with a simulator whose attestations are signed
+// by the CA in caFile. Enclaves that transfer keys between each other must share the CA.
+func simulatorOptions(caFile string, pcr0 string) ([]enclave2.Option, error) {
+	log.Warn("Using simulated Nitro Secure Module, attestations are not secure", "ca", caFile)
+	data, err := os.ReadFile(caFile)
+	if err != nil {
+		return nil, fmt.Errorf("error reading simulator CA: %w", err)
+	}
+	ca, err := nsmsim.ParseCA(data)
+	if err != nil {
+		return nil, fmt.Errorf("error parsing simulator CA: %w", err)
+	}
+	pcrs := make(map[uint][]byte)
+	if pcr0 != "" {
+		pcrs[0], err = hexutil.Decode(pcr0)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing simulator PCR0 %s: %w", pcr0, err)
+		}
+	}
+	sim, err := nsmsim.New(ca, pcrs)
+	if err != nil {
+		return nil, fmt.Errorf("error creating NSM simulator: %w", err)
+	}
+	provider, err := enclave2.NewNitroProvider(func() (enclave2.NSMSession, error) {
+		return sim.Open()
+	})
 	if err != nil {
-		log.Crit("Error starting server", "error", err)
+		return nil, fmt.Errorf("error opening NSM simulator session: %w", err)
 	}
+	return []enclave2.Option{
+		enclave2.WithAttestationProvider(provider),
+		enclave2.WithAttestationRoots(ca.Roots()),
+	}, nil
 }
@@ -0,0 +1,34 @@
+package main
+
+import (
+	"flag"
+	"os"
+
+	"github.com/base/op-enclave/op-enclave/nsmsim"
+)
+
+// nsmsim-ca generates a certificate authority for the NSM simulator. The CA file (certificate
+// and private key) is passed to the enclaves via OP_ENCLAVE_NSM_SIMULATOR_CA, and the
+// certificate to anything that verifies their attestations.
+func main() {
+	out := flag.String("out", "nsmsim-ca.pem", "file to write the CA certificate and private key to")
+	certOut := flag.String("cert-out", "", "optional file to write the CA certificate to")
+	flag.Parse()
+
+	ca, err := nsmsim.NewCA()
+	if err != nil {
+		panic(err)
+	}
+	data, err := ca.MarshalPEM()
+	if err != nil {
+		panic(err)
+	}
+	if err = os.WriteFile(*out, data, 0600); err != nil {
+		panic(err)
+	}
+	if *certOut != "" {
+		if err = os.WriteFile(*certOut, ca.CertificatePEM(), 0644); err != nil {
+			panic(err)
+		}
+	}
+}
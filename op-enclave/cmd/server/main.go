@@ -1,70 +1,129 @@
 package main
 
 import (
-	"encoding/json"
-	"io"
-	"log"
+	"context"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
-	"sync"
+	"os"
+	"time"
 
+	opservice "github.com/ethereum-optimism/optimism/op-service"
+	"github.com/ethereum-optimism/optimism/op-service/ctxinterrupt"
+	oplog "github.com/ethereum-optimism/optimism/op-service/log"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/mdlayher/vsock"
+	"github.com/urfave/cli/v2"
 )
 
-// small HTTP proxy that forwards requests to a vsock service
-func main() {
-	pool := sync.Pool{
-		New: func() any {
-			conn, err := vsock.Dial(16, 1234, &vsock.Config{})
-			if err != nil {
-				log.Printf("Error dialing vsock: %v", err)
-				return nil
-			}
-			return conn
-		},
-	}
+const EnvVarPrefix = "OP_ENCLAVE_SERVER"
 
-	handler := func(w http.ResponseWriter, r *http.Request) {
-		if r.Method != http.MethodPost {
-			w.WriteHeader(http.StatusOK)
-			return
-		}
-		req, err := io.ReadAll(r.Body)
-		if err != nil {
-			log.Printf("Error reading request body: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		_ = r.Body.Close()
+func prefixEnvVars(name string) []string {
+	return opservice.PrefixEnvVar(EnvVarPrefix, name)
+}
 
-		conn := pool.Get().(*vsock.Conn)
-		if conn == nil {
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
-		defer pool.Put(conn)
+var (
+	EnclaveCIDFlag = &cli.UintFlag{
+		Name:    "enclave-cid",
+		Usage:   "vsock context ID of the enclave",
+		EnvVars: prefixEnvVars("ENCLAVE_CID"),
+		Value:   16,
+	}
+	EnclavePortFlag = &cli.UintFlag{
+		Name:    "enclave-port",
+		Usage:   "vsock port the enclave listens on",
+		EnvVars: prefixEnvVars("ENCLAVE_PORT"),
+		Value:   1234,
+	}
+	ListenAddrFlag = &cli.StringFlag{
+		Name:    "listen-addr",
+		Usage:   "HTTP address to listen on",
+		EnvVars: prefixEnvVars("LISTEN_ADDR"),
+		Value:   ":7333",
+	}
+	MaxConnsFlag = &cli.IntFlag{
+		Name:    "max-conns",
+		Usage:   "Maximum number of concurrent connections to the enclave",
+		EnvVars: prefixEnvVars("MAX_CONNS"),
+		Value:   64,
+	}
+	RequestTimeoutFlag = &cli.DurationFlag{
+		Name:    "request-timeout",
+		Usage:   "Maximum duration of a request, including waiting for a free connection; large ranges of blocks can take minutes to execute",
+		EnvVars: prefixEnvVars("REQUEST_TIMEOUT"),
+		Value:   10 * time.Minute,
+	}
+	HealthTimeoutFlag = &cli.DurationFlag{
+		Name:    "health-timeout",
+		Usage:   "How long /healthz waits for the enclave to answer enclave_status",
+		EnvVars: prefixEnvVars("HEALTH_TIMEOUT"),
+		Value:   5 * time.Second,
+	}
+	MaxRequestSizeFlag = &cli.Int64Flag{
+		Name:    "max-request-size",
+		Usage:   "Maximum size of request bodies in bytes, which include execution witnesses",
+		EnvVars: prefixEnvVars("MAX_REQUEST_SIZE"),
+		Value:   512 * 1024 * 1024,
+	}
+)
 
-		_, err = conn.Write(req)
-		if err != nil {
-			log.Printf("Error writing to vsock: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+// small HTTP proxy that forwards requests to a vsock service
+func main() {
+	oplog.SetupDefaults()
 
-		dec := json.NewDecoder(conn)
-		dec.UseNumber()
+	app := cli.NewApp()
+	app.Flags = append([]cli.Flag{
+		EnclaveCIDFlag,
+		EnclavePortFlag,
+		ListenAddrFlag,
+		MaxConnsFlag,
+		RequestTimeoutFlag,
+		HealthTimeoutFlag,
+		MaxRequestSizeFlag,
+	}, oplog.CLIFlags(EnvVarPrefix)...)
+	app.Name = "op-enclave-server"
+	app.Usage = "HTTP proxy for the enclave RPC server"
+	app.Action = Main
+
+	ctx := ctxinterrupt.WithSignalWaiterMain(context.Background())
+	err := app.RunContext(ctx, os.Args)
+	if err != nil {
+		log.Crit("Application failed", "message", err)
+	}
+}
 
-		var raw json.RawMessage
-		if err := dec.Decode(&raw); err != nil {
-			log.Printf("Error decoding response: %v", err)
-			w.WriteHeader(http.StatusInternalServerError)
-			return
-		}
+func Main(cliCtx *cli.Context) error {
+	logger := oplog.NewLogger(oplog.AppOut(cliCtx), oplog.ReadCLIConfig(cliCtx))
+	oplog.SetGlobalLogHandler(logger.Handler())
+	opservice.ValidateEnvVars(EnvVarPrefix, cliCtx.App.Flags, logger)
 
-		_, _ = w.Write(raw)
+	maxConns := cliCtx.Int(MaxConnsFlag.Name)
+	if maxConns < 1 {
+		return fmt.Errorf("--%s must be at least 1", MaxConnsFlag.Name)
+	}
+	cid, port := uint32(cliCtx.Uint(EnclaveCIDFlag.Name)), uint32(cliCtx.Uint(EnclavePortFlag.Name))
+	p := &proxy{
+		log: logger,
+		pool: newConnPool(maxConns, func() (net.Conn, error) {
+			return vsock.Dial(cid, port, &vsock.Config{})
+		}),
+		requestTimeout: cliCtx.Duration(RequestTimeoutFlag.Name),
+		healthTimeout:  cliCtx.Duration(HealthTimeoutFlag.Name),
+		maxRequestSize: cliCtx.Int64(MaxRequestSizeFlag.Name),
 	}
 
-	err := http.ListenAndServe(":7333", http.HandlerFunc(handler))
-	if err != nil {
-		log.Fatalf("Error starting server: %v", err)
+	server := &http.Server{
+		Addr:    cliCtx.String(ListenAddrFlag.Name),
+		Handler: p,
+	}
+	go func() {
+		<-cliCtx.Context.Done()
+		_ = server.Close()
+	}()
+	logger.Info("Starting proxy", "addr", server.Addr, "enclave_cid", cid, "enclave_port", port)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("error starting server: %w", err)
 	}
+	return nil
 }
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"net"
+)
+
+// connPool is a bounded pool of connections to the enclave. At most cap(slots) connections
+// exist at once; requests beyond that wait for a connection to be released.
+type connPool struct {
+	dial  func() (net.Conn, error)
+	slots chan struct{}
+	idle  chan net.Conn
+}
+
+func newConnPool(size int, dial func() (net.Conn, error)) *connPool {
+	return &connPool{
+		dial:  dial,
+		slots: make(chan struct{}, size),
+		idle:  make(chan net.Conn, size),
+	}
+}
+
+// get returns an idle connection, or dials a new one. reused is true for idle connections,
+// which may have been closed by the enclave since they were last used.
+func (p *connPool) get(ctx context.Context) (conn net.Conn, reused bool, err error) {
+	select {
+	case p.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, false, ctx.Err()
+	}
+	select {
+	case conn = <-p.idle:
+		return conn, true, nil
+	default:
+	}
+	conn, err = p.dial()
+	if err != nil {
+		<-p.slots
+		return nil, false, err
+	}
+	return conn, false, nil
+}
+
+// put returns a healthy connection to the pool.
+func (p *connPool) put(conn net.Conn) {
+	select {
+	case p.idle <- conn:
+	default:
+		_ = conn.Close()
+	}
+	<-p.slots
+}
+
+// discard closes a connection that failed, so that a late or partial response from the enclave
+// can't be read by a later request.
+func (p *connPool) discard(conn net.Conn) {
+	_ = conn.Close()
+	<-p.slots
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// JSON-RPC error codes returned by the proxy itself.
+const (
+	parseErrorCode     = -32700
+	invalidRequestCode = -32600
+)
+
+var statusRequest = []byte(`{"jsonrpc":"2.0","id":1,"method":"enclave_status","params":[]}`)
+
+// proxy forwards JSON-RPC requests over HTTP to the enclave's RPC server. Each request is written
+// to a pooled connection, and exactly one JSON response is read back, unless the request only
+// contains notifications, which the enclave doesn't answer.
+type proxy struct {
+	log            log.Logger
+	pool           *connPool
+	requestTimeout time.Duration
+	healthTimeout  time.Duration
+	maxRequestSize int64
+}
+
+func (p *proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		// every non-POST request is a health check, so that existing load balancer probes
+		// of / also fail when the enclave is down
+		p.healthz(w, r)
+		return
+	}
+
+	req, err := io.ReadAll(http.MaxBytesReader(w, r.Body, p.maxRequestSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			http.Error(w, fmt.Sprintf("request exceeds %d bytes", p.maxRequestSize), http.StatusRequestEntityTooLarge)
+			return
+		}
+		p.log.Warn("Error reading request body", "err", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	// the enclave closes the connection on malformed input, and would answer multiple
+	// concatenated values separately, so only single well-formed messages are forwarded
+	expectResponse, err := inspectRequest(req)
+	if err != nil {
+		writeError(w, err)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), p.requestTimeout)
+	defer cancel()
+	res, err := p.forward(ctx, req, expectResponse)
+	if err != nil {
+		p.log.Warn("Error forwarding request", "err", err)
+		status := http.StatusBadGateway
+		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errTimeout) {
+			status = http.StatusGatewayTimeout
+		}
+		w.WriteHeader(status)
+		return
+	}
+	if res == nil {
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(res)
+}
+
+// healthz round-trips enclave_status to the enclave, and returns the status if it succeeds.
+func (p *proxy) healthz(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), p.healthTimeout)
+	defer cancel()
+	raw, err := p.forward(ctx, statusRequest, true)
+	if err != nil {
+		p.log.Warn("Health check failed", "err", err)
+		http.Error(w, err.Error(), http.StatusServiceUnavailable)
+		return
+	}
+	var res struct {
+		Result json.RawMessage `json:"result"`
+		Error  *struct {
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if err = json.Unmarshal(raw, &res); err != nil {
+		http.Error(w, fmt.Sprintf("invalid status response: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+	if res.Error != nil || len(res.Result) == 0 {
+		message := "empty status response"
+		if res.Error != nil {
+			message = res.Error.Message
+		}
+		http.Error(w, fmt.Sprintf("enclave status failed: %s", message), http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(res.Result)
+}
+
+var errTimeout = errors.New("enclave did not respond in time")
+
+// forward sends the request to the enclave and reads its response. A request that fails on a
+// reused connection before the enclave could have received it is retried once, since idle
+// connections may have been closed by an enclave restart.
+func (p *proxy) forward(ctx context.Context, req []byte, expectResponse bool) (json.RawMessage, error) {
+	res, retry, err := p.roundTrip(ctx, req, expectResponse)
+	if retry {
+		res, _, err = p.roundTrip(ctx, req, expectResponse)
+	}
+	return res, err
+}
+
+func (p *proxy) roundTrip(ctx context.Context, req []byte, expectResponse bool) (res json.RawMessage, retry bool, err error) {
+	conn, reused, err := p.pool.get(ctx)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to connect to enclave: %w", err)
+	}
+	// every connection that isn't returned to the pool healthy is discarded
+	healthy := false
+	defer func() {
+		if healthy {
+			p.pool.put(conn)
+		} else {
+			p.pool.discard(conn)
+		}
+	}()
+
+	deadline, _ := ctx.Deadline()
+	if err = conn.SetDeadline(deadline); err != nil {
+		return nil, false, fmt.Errorf("error setting deadline: %w", err)
+	}
+	// unblock reads and writes if the HTTP client goes away
+	stop := context.AfterFunc(ctx, func() {
+		_ = conn.SetDeadline(time.Now())
+	})
+	defer stop()
+
+	n, err := conn.Write(req)
+	if err != nil {
+		return nil, reused && n == 0 && ctx.Err() == nil, fmt.Errorf("error writing to enclave: %w", timeoutError(err))
+	}
+	if !expectResponse {
+		healthy = true
+		return nil, false, nil
+	}
+
+	dec := json.NewDecoder(conn)
+	if err = dec.Decode(&res); err != nil {
+		// EOF before any response on an idle connection means the enclave closed it while idle
+		return nil, reused && errors.Is(err, io.EOF) && ctx.Err() == nil, fmt.Errorf("error reading response: %w", timeoutError(err))
+	}
+	if !onlyWhitespace(dec.Buffered()) {
+		// data after the response means the connection is out of sync
+		return res, false, nil
+	}
+	healthy = true
+	return res, false, nil
+}
+
+func timeoutError(err error) error {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return errTimeout
+	}
+	return err
+}
+
+func onlyWhitespace(r io.Reader) bool {
+	rest, _ := io.ReadAll(r)
+	return len(bytes.TrimSpace(rest)) == 0
+}
+
+// rpcError is a JSON-RPC error response for a request the proxy rejected.
+type rpcError struct {
+	code    int
+	message string
+}
+
+func (e *rpcError) Error() string {
+	return e.message
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	var rpcErr *rpcError
+	if !errors.As(err, &rpcErr) {
+		rpcErr = &rpcError{code: invalidRequestCode, message: err.Error()}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      nil,
+		"error": map[string]any{
+			"code":    rpcErr.code,
+			"message": rpcErr.message,
+		},
+	})
+}
+
+// message is the subset of a JSON-RPC message needed to tell whether the enclave will answer it.
+type message struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params"`
+	Result  json.RawMessage `json:"result"`
+	Error   json.RawMessage `json:"error"`
+}
+
+// expectsResponse mirrors the geth RPC server, which doesn't answer notifications (calls
+// without an id) or messages that look like responses.
+func (m *message) expectsResponse() bool {
+	if m.Version != "2.0" {
+		return true
+	}
+	notification := m.ID == nil && m.Method != ""
+	response := len(m.ID) > 0 && m.Method == "" && m.Params == nil && (m.Result != nil || m.Error != nil)
+	return !notification && !response
+}
+
+// inspectRequest checks that the body is a single JSON-RPC message or batch, and returns whether
+// the enclave will answer it. A batch is answered unless none of its messages are; an empty
+// batch is answered with an error.
+func inspectRequest(req []byte) (bool, error) {
+	if !json.Valid(req) {
+		return false, &rpcError{code: parseErrorCode, message: "parse error"}
+	}
+	trimmed := bytes.TrimSpace(req)
+	if trimmed[0] == '[' {
+		var batch []*message
+		if err := json.Unmarshal(trimmed, &batch); err != nil {
+			return false, &rpcError{code: invalidRequestCode, message: "invalid batch request"}
+		}
+		if len(batch) == 0 {
+			return true, nil
+		}
+		for _, msg := range batch {
+			if msg == nil || msg.expectsResponse() {
+				return true, nil
+			}
+		}
+		return false, nil
+	}
+	var msg message
+	if err := json.Unmarshal(trimmed, &msg); err != nil {
+		return false, &rpcError{code: invalidRequestCode, message: "invalid request"}
+	}
+	return msg.expectsResponse(), nil
+}
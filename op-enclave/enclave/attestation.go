@@ -0,0 +1,87 @@
+package enclave
+
+import (
+	"bytes"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/hf/nitrite"
+)
+
+// VerifyOptions contains the expected contents of an attestation document. Empty fields are not checked.
+type VerifyOptions struct {
+	// Roots overrides the AWS Nitro root certificate, e.g. to verify attestations from a simulated NSM
+	Roots *x509.CertPool
+	// CurrentTime is the time the certificate chain must be valid at, or the current time if zero.
+	// This can be used to verify historical attestations, whose certificates have since expired.
+	CurrentTime time.Time
+
+	PCR0      []byte
+	PublicKey []byte
+	Nonce     []byte
+	UserData  []byte
+}
+
+// VerifyAttestation verifies the attestation document's certificate chain and signature
+// against the AWS Nitro root certificate, and checks that the document matches the options.
+// As with nitrite.Verify, if the document could be parsed but its certificate chain or
+// signature is invalid, the result is returned along with the error.
+func VerifyAttestation(attestation []byte, opts VerifyOptions) (*nitrite.Result, error) {
+	roots := opts.Roots
+	if roots == nil {
+		roots = defaultRoot
+	}
+	currentTime := opts.CurrentTime
+	if currentTime.IsZero() {
+		currentTime = time.Now()
+	}
+	res, err := nitrite.Verify(
+		attestation,
+		nitrite.VerifyOptions{
+			Roots:       roots,
+			CurrentTime: currentTime,
+		},
+	)
+	if err != nil {
+		return res, fmt.Errorf("failed to verify attestation: %w", err)
+	}
+	if opts.PCR0 != nil && !bytes.Equal(res.Document.PCRs[0], opts.PCR0) {
+		return nil, errors.New("attestation does not match PCR0")
+	}
+	if opts.PublicKey != nil && !bytes.Equal(res.Document.PublicKey, opts.PublicKey) {
+		return nil, errors.New("attestation does not match public key")
+	}
+	if opts.Nonce != nil && !bytes.Equal(res.Document.Nonce, opts.Nonce) {
+		return nil, errors.New("attestation does not match nonce")
+	}
+	if opts.UserData != nil && !bytes.Equal(res.Document.UserData, opts.UserData) {
+		return nil, errors.New("attestation does not match user data")
+	}
+	return res, nil
+}
+
+// DefaultRoots returns a copy of the built-in AWS Nitro root certificate pool.
+func DefaultRoots() *x509.CertPool {
+	return defaultRoot.Clone()
+}
+
+// ParseRoots parses a bundle of PEM encoded root certificates.
+func ParseRoots(data []byte) (*x509.CertPool, error) {
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, errors.New("no certificates found in PEM data")
+	}
+	return pool, nil
+}
+
+// LoadRoots reads a bundle of PEM encoded root certificates from a file.
+func LoadRoots(file string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read roots: %w", err)
+	}
+	return ParseRoots(data)
+}
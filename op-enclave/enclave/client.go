@@ -2,6 +2,7 @@ package enclave
 
 import (
 	"context"
+	"errors"
 
 	"github.com/ethereum-optimism/optimism/op-service/eth"
 	"github.com/ethereum/go-ethereum/common"
@@ -11,6 +12,9 @@ import (
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
+// methodNotFoundCode is the JSON-RPC error code returned for unknown methods.
+const methodNotFoundCode = -32601
+
 type Client struct {
 	*rpc.Client
 }
@@ -26,9 +30,9 @@ func (c *Client) SignerPublicKey(ctx context.Context) (hexutil.Bytes, error) {
 	return result, c.callContext(ctx, &result, "signerPublicKey")
 }
 
-func (c *Client) SignerAttestation(ctx context.Context) (hexutil.Bytes, error) {
+func (c *Client) SignerAttestation(ctx context.Context, nonce *hexutil.Bytes, userData *hexutil.Bytes) (hexutil.Bytes, error) {
 	var result hexutil.Bytes
-	return result, c.callContext(ctx, &result, "signerAttestation")
+	return result, c.callContext(ctx, &result, "signerAttestation", nonce, userData)
 }
 
 func (c *Client) DecryptionPublicKey(ctx context.Context) (hexutil.Bytes, error) {
@@ -36,9 +40,9 @@ func (c *Client) DecryptionPublicKey(ctx context.Context) (hexutil.Bytes, error)
 	return result, c.callContext(ctx, &result, "decryptionPublicKey")
 }
 
-func (c *Client) DecryptionAttestation(ctx context.Context) (hexutil.Bytes, error) {
+func (c *Client) DecryptionAttestation(ctx context.Context, nonce *hexutil.Bytes, userData *hexutil.Bytes) (hexutil.Bytes, error) {
 	var result hexutil.Bytes
-	return result, c.callContext(ctx, &result, "decryptionAttestation")
+	return result, c.callContext(ctx, &result, "decryptionAttestation", nonce, userData)
 }
 
 func (c *Client) EncryptedSignerKey(ctx context.Context, attestation hexutil.Bytes) (hexutil.Bytes, error) {
@@ -46,8 +50,28 @@ func (c *Client) EncryptedSignerKey(ctx context.Context, attestation hexutil.Byt
 	return result, c.callContext(ctx, &result, "encryptedSignerKey", attestation)
 }
 
-func (c *Client) SetSignerKey(ctx context.Context, encrypted hexutil.Bytes) error {
-	return c.callContext(ctx, nil, "setSignerKey", encrypted)
+func (c *Client) SetSignerKey(ctx context.Context, encrypted hexutil.Bytes, attestation hexutil.Bytes) error {
+	return c.callContext(ctx, nil, "setSignerKey", encrypted, attestation)
+}
+
+func (c *Client) SetTrustedPCR0s(ctx context.Context, trusted *TrustedPCR0s) error {
+	return c.callContext(ctx, nil, "setTrustedPCR0s", trusted)
+}
+
+func (c *Client) Capabilities(ctx context.Context) ([]string, error) {
+	var result []string
+	err := c.callContext(ctx, &result, "capabilities")
+	var rpcErr rpc.Error
+	if errors.As(err, &rpcErr) && rpcErr.ErrorCode() == methodNotFoundCode {
+		// enclaves that predate capability advertisement support no optional features
+		return nil, nil
+	}
+	return result, err
+}
+
+func (c *Client) Status(ctx context.Context) (*Status, error) {
+	var result Status
+	return &result, c.callContext(ctx, &result, "status")
 }
 
 func (c *Client) ExecuteStateless(ctx context.Context, config *PerChainConfig, l1Origin *types.Header, l1Receipts types.Receipts, previousBlockTxs []hexutil.Bytes, blockHeader *types.Header, sequencedTxs []hexutil.Bytes, witness *stateless.ExecutionWitness, messageAccount *eth.AccountResult, prevMessageAccountHash common.Hash) (*Proposal, error) {
@@ -55,7 +79,17 @@ func (c *Client) ExecuteStateless(ctx context.Context, config *PerChainConfig, l
 	return &result, c.callContext(ctx, &result, "executeStateless", config, l1Origin, l1Receipts, previousBlockTxs, blockHeader, sequencedTxs, witness, messageAccount, prevMessageAccountHash)
 }
 
+func (c *Client) ExecuteStatelessRange(ctx context.Context, config *PerChainConfig, blocks []*StatelessBlock) (*Proposal, error) {
+	var result Proposal
+	return &result, c.callContext(ctx, &result, "executeStatelessRange", config, blocks)
+}
+
 func (c *Client) Aggregate(ctx context.Context, configHash common.Hash, prevOutputRoot common.Hash, proposals []*Proposal) (*Proposal, error) {
 	var result Proposal
 	return &result, c.callContext(ctx, &result, "aggregate", configHash, prevOutputRoot, proposals)
 }
+
+func (c *Client) AnchorProposal(ctx context.Context, configHash common.Hash, prevOutputRoot common.Hash, proposal *Proposal, l1Headers []*types.Header) (*Proposal, error) {
+	var result Proposal
+	return &result, c.callContext(ctx, &result, "anchorProposal", configHash, prevOutputRoot, proposal, l1Headers)
+}
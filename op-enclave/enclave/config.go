@@ -2,7 +2,11 @@ package enclave
 
 import (
 	"encoding/binary"
+	"errors"
+	"fmt"
+	"maps"
 	"math/big"
+	"slices"
 
 	"github.com/ethereum-optimism/optimism/op-chain-ops/genesis"
 	"github.com/ethereum-optimism/optimism/op-node/rollup"
@@ -16,8 +20,12 @@ import (
 
 const (
 	version0 uint64 = 0
+	version1 uint64 = 1
 )
 
+// configVersions are the PerChainConfig versions that can be hashed and executed.
+var configVersions = []uint64{version0, version1}
+
 var (
 	l2GenesisBlockBaseFeePerGas = hexutil.Big(*(big.NewInt(1000000000)))
 	vaultMinWithdrawalAmount    = mustHexBigFromHex("0x8ac7230489e80000")
@@ -26,6 +34,9 @@ var (
 var chainConfigTemplate params.ChainConfig
 var rollupConfigTemplate rollup.Config
 
+// templateForks are the fork activation times of version 0 configs.
+var templateForks map[rollup.ForkName]uint64
+
 func init() {
 	deployConfig := DefaultDeployConfig()
 
@@ -34,6 +45,10 @@ func init() {
 	if err != nil {
 		panic(err)
 	}
+	templateForks, err = forksFromRollupConfig(&rollupConfigTemplate)
+	if err != nil {
+		panic(err)
+	}
 }
 
 type ChainConfig struct {
@@ -74,32 +89,69 @@ func NewChainConfig(cfg *PerChainConfig) *ChainConfig {
 	cfg.ForceDefaults()
 	chainConfig := chainConfigTemplate
 	chainConfig.ChainID = cfg.ChainID
+	if cfg.Version != version0 {
+		chainConfig.RegolithTime = cfg.forkTime(rollup.Regolith)
+		chainConfig.CanyonTime = cfg.forkTime(rollup.Canyon)
+		chainConfig.ShanghaiTime = cfg.forkTime(rollup.Canyon)
+		chainConfig.EcotoneTime = cfg.forkTime(rollup.Ecotone)
+		chainConfig.CancunTime = cfg.forkTime(rollup.Ecotone)
+		chainConfig.FjordTime = cfg.forkTime(rollup.Fjord)
+		chainConfig.GraniteTime = cfg.forkTime(rollup.Granite)
+		chainConfig.HoloceneTime = cfg.forkTime(rollup.Holocene)
+		chainConfig.IsthmusTime = cfg.forkTime(rollup.Isthmus)
+		chainConfig.PragueTime = cfg.forkTime(rollup.Isthmus)
+	}
 	return &ChainConfig{
 		ChainConfig:    &chainConfig,
 		PerChainConfig: cfg,
 	}
 }
 
+// PerChainConfig is the chain specific configuration that the enclave executes blocks with,
+// and that proposals commit to through its hash. Version 0 configs only encode the genesis and
+// contract addresses, and use the template defaults for the block time, sequencer drift,
+// sequencer window and fork activation times. Version 1 configs encode these too.
 type PerChainConfig struct {
+	Version uint64   `json:"version"`
 	ChainID *big.Int `json:"chain_id"`
 
-	Genesis   rollup.Genesis `json:"genesis"`
-	BlockTime uint64         `json:"block_time"`
+	Genesis           rollup.Genesis `json:"genesis"`
+	BlockTime         uint64         `json:"block_time"`
+	MaxSequencerDrift uint64         `json:"max_sequencer_drift,omitempty"`
+	SeqWindowSize     uint64         `json:"seq_window_size,omitempty"`
+
+	// Forks are the activation times of the hardforks after Bedrock. Forks that aren't
+	// listed are never activated.
+	Forks map[rollup.ForkName]uint64 `json:"forks,omitempty"`
 
 	DepositContractAddress common.Address `json:"deposit_contract_address"`
 	L1SystemConfigAddress  common.Address `json:"l1_system_config_address"`
 }
 
-func FromRollupConfig(cfg *rollup.Config) *PerChainConfig {
+// FromRollupConfig returns the config of the given version for the rollup config. It returns an
+// error if the version encodes forks, and the rollup config schedules a fork that the enclave
+// can't execute.
+func FromRollupConfig(cfg *rollup.Config, version uint64) (*PerChainConfig, error) {
+	var forks map[rollup.ForkName]uint64
+	if version != version0 {
+		var err error
+		if forks, err = forksFromRollupConfig(cfg); err != nil {
+			return nil, err
+		}
+	}
 	p := &PerChainConfig{
+		Version:                version,
 		ChainID:                cfg.L2ChainID,
 		Genesis:                cfg.Genesis,
 		BlockTime:              cfg.BlockTime,
+		MaxSequencerDrift:      cfg.MaxSequencerDrift,
+		SeqWindowSize:          cfg.SeqWindowSize,
+		Forks:                  forks,
 		DepositContractAddress: cfg.DepositContractAddress,
 		L1SystemConfigAddress:  cfg.L1SystemConfigAddress,
 	}
 	p.ForceDefaults()
-	return p
+	return p, nil
 }
 
 func (p *PerChainConfig) ToRollupConfig() *rollup.Config {
@@ -107,15 +159,65 @@ func (p *PerChainConfig) ToRollupConfig() *rollup.Config {
 	cfg.L2ChainID = p.ChainID
 	cfg.Genesis = p.Genesis
 	cfg.BlockTime = p.BlockTime
+	cfg.MaxSequencerDrift = p.MaxSequencerDrift
+	cfg.SeqWindowSize = p.SeqWindowSize
+	for fork, t := range rollupForkTimes(&cfg) {
+		*t = p.forkTime(fork)
+	}
 	cfg.DepositContractAddress = p.DepositContractAddress
 	cfg.L1SystemConfigAddress = p.L1SystemConfigAddress
 	return &cfg
 }
 
+// ForceDefaults resets the fields that the config's version doesn't encode, so that configs
+// with the same hash are always executed the same way.
 func (p *PerChainConfig) ForceDefaults() {
-	p.BlockTime = 1
 	p.Genesis.L2.Number = 0
 	p.Genesis.SystemConfig.Overhead = eth.Bytes32{}
+	if p.Version != version0 {
+		return
+	}
+	p.BlockTime = 1
+	p.MaxSequencerDrift = rollupConfigTemplate.MaxSequencerDrift
+	p.SeqWindowSize = rollupConfigTemplate.SeqWindowSize
+	p.Forks = maps.Clone(templateForks)
+}
+
+// Check returns an error if the config's version is unsupported, or if its chain parameters
+// can't be executed.
+func (p *PerChainConfig) Check() error {
+	switch p.Version {
+	case version0:
+		return nil
+	case version1:
+	default:
+		return fmt.Errorf("unsupported config version %d", p.Version)
+	}
+	if p.ChainID == nil {
+		return errors.New("missing chain ID")
+	}
+	if p.BlockTime == 0 {
+		return errors.New("block time must be greater than 0")
+	}
+	if p.SeqWindowSize < 2 {
+		return errors.New("sequencer window size must be at least 2")
+	}
+	if err := checkForks(p.Forks); err != nil {
+		return err
+	}
+	// Fjord makes the sequencer drift a constant, so it's only needed for blocks before Fjord
+	if fjord := p.forkTime(rollup.Fjord); p.MaxSequencerDrift == 0 && (fjord == nil || *fjord > p.Genesis.L2Time) {
+		return errors.New("max sequencer drift must be set if Fjord is not active at genesis")
+	}
+	return nil
+}
+
+func (p *PerChainConfig) forkTime(fork rollup.ForkName) *uint64 {
+	t, ok := p.Forks[fork]
+	if !ok {
+		return nil
+	}
+	return &t
 }
 
 func (p *PerChainConfig) Hash() common.Hash {
@@ -123,7 +225,7 @@ func (p *PerChainConfig) Hash() common.Hash {
 }
 
 func (p *PerChainConfig) MarshalBinary() (data []byte) {
-	data = binary.BigEndian.AppendUint64(data, version0)
+	data = binary.BigEndian.AppendUint64(data, p.Version)
 	chainIDBytes := p.ChainID.Bytes()
 	data = append(data, make([]byte, 32-len(chainIDBytes))...)
 	data = append(data, chainIDBytes...)
@@ -135,9 +237,100 @@ func (p *PerChainConfig) MarshalBinary() (data []byte) {
 	data = binary.BigEndian.AppendUint64(data, p.Genesis.SystemConfig.GasLimit)
 	data = append(data, p.DepositContractAddress.Bytes()...)
 	data = append(data, p.L1SystemConfigAddress.Bytes()...)
+	if p.Version == version0 {
+		return data
+	}
+	data = binary.BigEndian.AppendUint64(data, p.BlockTime)
+	data = binary.BigEndian.AppendUint64(data, p.MaxSequencerDrift)
+	data = binary.BigEndian.AppendUint64(data, p.SeqWindowSize)
+	for _, fork := range supportedForks {
+		data = appendTime(data, p.forkTime(fork))
+	}
 	return data
 }
 
+// appendTime encodes an optional fork activation time as a presence byte followed by the time,
+// so that a fork that is never activated is distinguishable from any activation time.
+func appendTime(data []byte, t *uint64) []byte {
+	if t == nil {
+		return append(data, make([]byte, 9)...)
+	}
+	data = append(data, 1)
+	return binary.BigEndian.AppendUint64(data, *t)
+}
+
+// supportedForks are the hardforks after Bedrock that the enclave can execute, in activation
+// order. Each config version encodes a fixed list of forks, so supporting another fork requires
+// a new config version.
+var supportedForks = []rollup.ForkName{
+	rollup.Regolith,
+	rollup.Canyon,
+	rollup.Delta,
+	rollup.Ecotone,
+	rollup.Fjord,
+	rollup.Granite,
+	rollup.Holocene,
+	rollup.Isthmus,
+}
+
+// rollupForkTimes returns the activation time fields of the rollup config by fork, including
+// forks that the enclave doesn't support.
+func rollupForkTimes(cfg *rollup.Config) map[rollup.ForkName]**uint64 {
+	return map[rollup.ForkName]**uint64{
+		rollup.Regolith: &cfg.RegolithTime,
+		rollup.Canyon:   &cfg.CanyonTime,
+		rollup.Delta:    &cfg.DeltaTime,
+		rollup.Ecotone:  &cfg.EcotoneTime,
+		rollup.Fjord:    &cfg.FjordTime,
+		rollup.Granite:  &cfg.GraniteTime,
+		rollup.Holocene: &cfg.HoloceneTime,
+		rollup.Isthmus:  &cfg.IsthmusTime,
+		rollup.Interop:  &cfg.InteropTime,
+	}
+}
+
+func forksFromRollupConfig(cfg *rollup.Config) (map[rollup.ForkName]uint64, error) {
+	if cfg.PectraBlobScheduleTime != nil {
+		return nil, errors.New("pectra blob schedule fix is not supported")
+	}
+	forks := make(map[rollup.ForkName]uint64)
+	for fork, t := range rollupForkTimes(cfg) {
+		if *t == nil {
+			continue
+		}
+		if !slices.Contains(supportedForks, fork) {
+			return nil, fmt.Errorf("fork %s is not supported", fork)
+		}
+		forks[fork] = **t
+	}
+	return forks, nil
+}
+
+// checkForks returns an error if a fork is unknown, or if a fork is scheduled without, or
+// before, the fork preceding it.
+func checkForks(forks map[rollup.ForkName]uint64) error {
+	for fork := range forks {
+		if !slices.Contains(supportedForks, fork) {
+			return fmt.Errorf("unknown fork %s", fork)
+		}
+	}
+	for i := 1; i < len(supportedForks); i++ {
+		prev, fork := supportedForks[i-1], supportedForks[i]
+		t, ok := forks[fork]
+		if !ok {
+			continue
+		}
+		prevTime, ok := forks[prev]
+		if !ok {
+			return fmt.Errorf("fork %s is scheduled, but prior fork %s is not", fork, prev)
+		}
+		if prevTime > t {
+			return fmt.Errorf("fork %s is scheduled at %d, before prior fork %s at %d", fork, t, prev, prevTime)
+		}
+	}
+	return nil
+}
+
 func DefaultDeployConfig() genesis.DeployConfig {
 	return genesis.DeployConfig{
 		L2InitializationConfig: genesis.L2InitializationConfig{
@@ -0,0 +1,216 @@
+package enclave
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// Signer key envelope versions, stored in the first byte of the envelope.
+const (
+	// envelopeVersion0 is the raw signer key encrypted with RSA PKCS#1 v1.5. Enclaves that
+	// predate versioned envelopes send this format without the version byte. It is only
+	// decrypted, so that keys can be migrated from those enclaves, and never sealed.
+	envelopeVersion0 byte = 0
+	// envelopeVersion1 is the signer key encrypted with AES-256-GCM, with the AES key wrapped
+	// using RSA-OAEP (SHA-256). The envelope header, which contains the nonce and the sender's
+	// PCR0, is used as both the OAEP label and the GCM additional data. The envelope is signed
+	// by the signer key it contains.
+	//
+	//	version (1) || nonce || pcr0 || wrapped AES key || GCM nonce || ciphertext || signature (65)
+	//
+	// where all fields except the version, ciphertext and signature are prefixed with a uint16 length.
+	envelopeVersion1 byte = 1
+
+	envelopeSignatureLength = crypto.SignatureLength
+)
+
+// signerKeyEnvelope is a decrypted signer key envelope.
+type signerKeyEnvelope struct {
+	version byte
+	nonce   []byte
+	pcr0    []byte
+	key     *ecdsa.PrivateKey
+	// signer is the public key that signed the envelope, or nil for unsigned versions
+	signer *ecdsa.PublicKey
+}
+
+// sealSignerKey encrypts the signer key to the given public key, using the latest envelope version.
+func sealSignerKey(random io.Reader, public *rsa.PublicKey, signerKey *ecdsa.PrivateKey, pcr0 []byte, nonce []byte) ([]byte, error) {
+	header, err := appendField([]byte{envelopeVersion1}, nonce)
+	if err != nil {
+		return nil, err
+	}
+	if header, err = appendField(header, pcr0); err != nil {
+		return nil, err
+	}
+
+	aesKey := make([]byte, 32)
+	if _, err = io.ReadFull(random, aesKey); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	gcmNonce := make([]byte, gcm.NonceSize())
+	if _, err = io.ReadFull(random, gcmNonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	wrappedKey, err := rsa.EncryptOAEP(sha256.New(), random, public, aesKey, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to wrap key: %w", err)
+	}
+
+	envelope := append([]byte{}, header...)
+	if envelope, err = appendField(envelope, wrappedKey); err != nil {
+		return nil, err
+	}
+	if envelope, err = appendField(envelope, gcmNonce); err != nil {
+		return nil, err
+	}
+	envelope = gcm.Seal(envelope, gcmNonce, crypto.FromECDSA(signerKey), header)
+	sig, err := crypto.Sign(crypto.Keccak256(envelope), signerKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign envelope: %w", err)
+	}
+	return append(envelope, sig...), nil
+}
+
+// openSignerKey decrypts a signer key envelope of any supported version.
+func openSignerKey(random io.Reader, private *rsa.PrivateKey, envelope []byte) (*signerKeyEnvelope, error) {
+	if len(envelope) == private.Size() {
+		// unversioned legacy envelope, which is shorter than any versioned one
+		return openSignerKeyV0(random, private, envelope)
+	}
+	if len(envelope) == 0 {
+		return nil, errors.New("empty envelope")
+	}
+	switch envelope[0] {
+	case envelopeVersion0:
+		return openSignerKeyV0(random, private, envelope[1:])
+	case envelopeVersion1:
+		return openSignerKeyV1(random, private, envelope)
+	default:
+		return nil, fmt.Errorf("unsupported envelope version: %d", envelope[0])
+	}
+}
+
+// openSignerKeyV0 decrypts a legacy envelope. A ciphertext with invalid padding decrypts to a
+// random key rather than an error, so that callers can't use it as a padding oracle; the key
+// then fails the check against the attested signer public key.
+func openSignerKeyV0(random io.Reader, private *rsa.PrivateKey, ciphertext []byte) (*signerKeyEnvelope, error) {
+	decrypted := make([]byte, 32)
+	if _, err := io.ReadFull(random, decrypted); err != nil {
+		return nil, fmt.Errorf("failed to generate key: %w", err)
+	}
+	if err := rsa.DecryptPKCS1v15SessionKey(random, private, ciphertext, decrypted); err != nil {
+		return nil, fmt.Errorf("failed to decrypt key: %w", err)
+	}
+	key, err := crypto.ToECDSA(decrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert key: %w", err)
+	}
+	return &signerKeyEnvelope{
+		version: envelopeVersion0,
+		key:     key,
+	}, nil
+}
+
+func openSignerKeyV1(random io.Reader, private *rsa.PrivateKey, envelope []byte) (*signerKeyEnvelope, error) {
+	if len(envelope) < 1+envelopeSignatureLength {
+		return nil, io.ErrUnexpectedEOF
+	}
+	sig := envelope[len(envelope)-envelopeSignatureLength:]
+	envelope = envelope[:len(envelope)-envelopeSignatureLength]
+	signer, err := crypto.SigToPub(crypto.Keccak256(envelope), sig)
+	if err != nil {
+		return nil, fmt.Errorf("failed to recover envelope signer: %w", err)
+	}
+
+	rest := envelope[1:]
+	nonce, rest, err := readField(rest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read nonce: %w", err)
+	}
+	pcr0, rest, err := readField(rest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read PCR0: %w", err)
+	}
+	header := envelope[:len(envelope)-len(rest)]
+	wrappedKey, rest, err := readField(rest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read wrapped key: %w", err)
+	}
+	gcmNonce, ciphertext, err := readField(rest)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read GCM nonce: %w", err)
+	}
+
+	aesKey, err := rsa.DecryptOAEP(sha256.New(), random, private, wrappedKey, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unwrap key: %w", err)
+	}
+	gcm, err := newGCM(aesKey)
+	if err != nil {
+		return nil, err
+	}
+	if len(gcmNonce) != gcm.NonceSize() {
+		return nil, errors.New("invalid GCM nonce size")
+	}
+	decrypted, err := gcm.Open(nil, gcmNonce, ciphertext, header)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt key: %w", err)
+	}
+	key, err := crypto.ToECDSA(decrypted)
+	if err != nil {
+		return nil, fmt.Errorf("failed to convert key: %w", err)
+	}
+	return &signerKeyEnvelope{
+		version: envelopeVersion1,
+		nonce:   nonce,
+		pcr0:    pcr0,
+		key:     key,
+		signer:  signer,
+	}, nil
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create GCM: %w", err)
+	}
+	return gcm, nil
+}
+
+func appendField(data []byte, field []byte) ([]byte, error) {
+	if len(field) > 0xffff {
+		return nil, errors.New("envelope field too long")
+	}
+	data = binary.BigEndian.AppendUint16(data, uint16(len(field)))
+	return append(data, field...), nil
+}
+
+func readField(data []byte) (field []byte, rest []byte, err error) {
+	if len(data) < 2 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	length := int(binary.BigEndian.Uint16(data))
+	data = data[2:]
+	if len(data) < length {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	return data[:length], data[length:], nil
+}
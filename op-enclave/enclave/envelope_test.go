@@ -0,0 +1,94 @@
+package enclave
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSignerKeyEnvelope(t *testing.T) {
+	decryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signerKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	pcr0 := []byte("pcr0")
+	nonce := []byte("nonce")
+
+	sealed, err := sealSignerKey(rand.Reader, &decryptionKey.PublicKey, signerKey, pcr0, nonce)
+	require.NoError(t, err)
+	require.Equal(t, envelopeVersion1, sealed[0])
+
+	t.Run("open", func(t *testing.T) {
+		envelope, err := openSignerKey(rand.Reader, decryptionKey, sealed)
+		require.NoError(t, err)
+		require.Equal(t, envelopeVersion1, envelope.version)
+		require.Equal(t, nonce, envelope.nonce)
+		require.Equal(t, pcr0, envelope.pcr0)
+		require.True(t, envelope.key.Equal(signerKey))
+		require.True(t, envelope.signer.Equal(&signerKey.PublicKey))
+	})
+
+	t.Run("wrong decryption key", func(t *testing.T) {
+		other, err := rsa.GenerateKey(rand.Reader, 2048)
+		require.NoError(t, err)
+		_, err = openSignerKey(rand.Reader, other, sealed)
+		require.ErrorContains(t, err, "failed to unwrap key")
+	})
+
+	t.Run("tampered", func(t *testing.T) {
+		// each offset falls into a different part of the envelope: the nonce and PCR0 in the
+		// header, and the ciphertext just before the signature
+		for _, offset := range []int{3, 3 + len(nonce) + 2, len(sealed) - envelopeSignatureLength - 1} {
+			tampered := append([]byte{}, sealed...)
+			tampered[offset] ^= 0xff
+			_, err := openSignerKey(rand.Reader, decryptionKey, tampered)
+			require.Error(t, err, "offset %d", offset)
+		}
+
+		// the signature is recovered rather than checked, so tampering with it changes the signer
+		tampered := append([]byte{}, sealed...)
+		tampered[len(tampered)-2] ^= 0xff
+		envelope, err := openSignerKey(rand.Reader, decryptionKey, tampered)
+		if err == nil {
+			require.False(t, envelope.signer.Equal(&signerKey.PublicKey))
+		}
+	})
+
+	t.Run("truncated", func(t *testing.T) {
+		for _, length := range []int{0, 1, 2 + envelopeSignatureLength, len(sealed) - 1} {
+			_, err := openSignerKey(rand.Reader, decryptionKey, sealed[:length])
+			require.Error(t, err, "length %d", length)
+		}
+	})
+}
+
+func TestSignerKeyEnvelopeLegacy(t *testing.T) {
+	decryptionKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	signerKey, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	legacy, err := rsa.EncryptPKCS1v15(rand.Reader, &decryptionKey.PublicKey, crypto.FromECDSA(signerKey))
+	require.NoError(t, err)
+
+	// enclaves that predate versioned envelopes send the ciphertext without a version byte
+	for _, sealed := range [][]byte{legacy, append([]byte{envelopeVersion0}, legacy...)} {
+		envelope, err := openSignerKey(rand.Reader, decryptionKey, sealed)
+		require.NoError(t, err)
+		require.Equal(t, envelopeVersion0, envelope.version)
+		require.True(t, envelope.key.Equal(signerKey))
+		require.Nil(t, envelope.signer)
+	}
+
+	// invalid padding decrypts to a random key instead of an error
+	tampered := append([]byte{}, legacy...)
+	tampered[len(tampered)-1] ^= 0xff
+	envelope, err := openSignerKey(rand.Reader, decryptionKey, tampered)
+	require.NoError(t, err)
+	require.False(t, envelope.key.Equal(signerKey))
+
+	_, err = openSignerKey(rand.Reader, decryptionKey, append([]byte{2}, legacy...))
+	require.ErrorContains(t, err, "unsupported envelope version")
+}
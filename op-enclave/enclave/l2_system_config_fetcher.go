@@ -10,6 +10,7 @@ import (
 	"github.com/ethereum-optimism/optimism/op-node/rollup/derive"
 	"github.com/ethereum-optimism/optimism/op-service/eth"
 	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/consensus/misc/eip1559"
 	"github.com/ethereum/go-ethereum/core/types"
 )
 
@@ -36,7 +37,7 @@ func (l *l2SystemConfigFetcher) SystemConfigByL2Hash(ctx context.Context, hash c
 	return blockToSystemConfig(l.config, l.header, l.txs)
 }
 
-// Copy of https://github.com/ethereum-optimism/optimism/blob/8b61225d51105b142580d40bde43adde791423b8/op-node/rollup/derive/payload_util.go#L54
+// Copy of https://github.com/ethereum-optimism/optimism/blob/v1.12.2/op-node/rollup/derive/payload_util.go#L55
 // but takes a header/txs rather than an execution payload.
 func blockToSystemConfig(rollupCfg *rollup.Config, header *types.Header, txs []*types.Transaction) (eth.SystemConfig, error) {
 	hash := header.Hash()
@@ -67,12 +68,26 @@ func blockToSystemConfig(rollupCfg *rollup.Config, header *types.Header, txs []*
 			binary.BigEndian.PutUint32(info.L1FeeScalar[24:28], info.BlobBaseFeeScalar)
 			binary.BigEndian.PutUint32(info.L1FeeScalar[28:32], info.BaseFeeScalar)
 		}
-		return eth.SystemConfig{
+		r := eth.SystemConfig{
 			BatcherAddr: info.BatcherAddr,
 			Overhead:    info.L1FeeOverhead,
 			Scalar:      info.L1FeeScalar,
 			GasLimit:    header.GasLimit,
-		}, err
+		}
+		if rollupCfg.IsHolocene(header.Time) {
+			if err := eip1559.ValidateHoloceneExtraData(header.Extra); err != nil {
+				return eth.SystemConfig{}, err
+			}
+			d, e := eip1559.DecodeHoloceneExtraData(header.Extra)
+			copy(r.EIP1559Params[:], eip1559.EncodeHolocene1559Params(d, e))
+		}
+		if rollupCfg.IsIsthmus(header.Time) {
+			r.OperatorFeeParams = eth.EncodeOperatorFeeParams(eth.OperatorFeeParams{
+				Scalar:   info.OperatorFeeScalar,
+				Constant: info.OperatorFeeConstant,
+			})
+		}
+		return r, nil
 	}
 }
 
@@ -0,0 +1,86 @@
+package enclave
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/hf/nsm"
+	"github.com/hf/nsm/request"
+	"github.com/hf/nsm/response"
+)
+
+// NSMSession is a session with a Nitro Secure Module. It is implemented by *nsm.Session,
+// and by the software simulator in the nsmsim package.
+type NSMSession interface {
+	Read(into []byte) (int, error)
+	Send(req request.Request) (response.Response, error)
+	Close() error
+}
+
+func openDefaultSession() (NSMSession, error) {
+	session, err := nsm.OpenDefaultSession()
+	if err != nil {
+		return nil, err
+	}
+	return session, nil
+}
+
+// NitroProvider is an AttestationProvider backed by an AWS Nitro Secure Module (or a simulation of one).
+type NitroProvider struct {
+	session NSMSession
+}
+
+var _ AttestationProvider = (*NitroProvider)(nil)
+
+// NewNitroProvider opens a session with the Nitro Secure Module, which is kept open until Close is called.
+func NewNitroProvider(open func() (NSMSession, error)) (*NitroProvider, error) {
+	session, err := open()
+	if err != nil {
+		return nil, fmt.Errorf("failed to open session: %w", err)
+	}
+	return &NitroProvider{
+		session: session,
+	}, nil
+}
+
+func (p *NitroProvider) Read(into []byte) (int, error) {
+	return p.session.Read(into)
+}
+
+func (p *NitroProvider) PCR(index uint16) ([]byte, error) {
+	res, err := p.session.Send(&request.DescribePCR{
+		Index: index,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe PCR: %w", err)
+	}
+	if res.Error != "" {
+		return nil, fmt.Errorf("NSM device returned an error: %s", res.Error)
+	}
+	if res.DescribePCR == nil || len(res.DescribePCR.Data) == 0 {
+		return nil, errors.New("NSM device did not return PCR data")
+	}
+	return res.DescribePCR.Data, nil
+}
+
+func (p *NitroProvider) Attestation(publicKey, userData, nonce []byte) ([]byte, error) {
+	res, err := p.session.Send(&request.Attestation{
+		PublicKey: publicKey,
+		UserData:  userData,
+		Nonce:     nonce,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get attestation: %w", err)
+	}
+	if res.Error != "" {
+		return nil, fmt.Errorf("NSM device returned an error: %s", res.Error)
+	}
+	if res.Attestation == nil || res.Attestation.Document == nil {
+		return nil, errors.New("NSM device did not return an attestation")
+	}
+	return res.Attestation.Document, nil
+}
+
+func (p *NitroProvider) Close() error {
+	return p.session.Close()
+}
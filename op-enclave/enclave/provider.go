@@ -0,0 +1,101 @@
+package enclave
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	"github.com/base/op-enclave/op-enclave/nsmsim"
+)
+
+// AttestationProvider provides the functions of a trusted execution environment that the
+// Server depends on: a source of randomness, reads of the platform configuration registers
+// (PCRs) that measure the running image, and attestation documents binding data to those PCRs.
+type AttestationProvider interface {
+	// Read fills into with random bytes.
+	io.Reader
+	// PCR returns the value of the PCR with the given index.
+	PCR(index uint16) ([]byte, error)
+	// Attestation returns an attestation document containing the given public key, user data and nonce, which may be empty.
+	Attestation(publicKey, userData, nonce []byte) ([]byte, error)
+	Close() error
+}
+
+// SoftwareProvider is an AttestationProvider without any hardware backing, for tests and
+// local development. Its randomness is a deterministic stream derived from a seed, and its PCRs
+// are fixed. Attestation documents are signed by the given NSM simulator CA, or are unavailable
+// if no CA is provided.
+//
+// Note that crypto/rsa and crypto/ecdsa deliberately don't derive keys deterministically from
+// the reader, so keys generated from a SoftwareProvider still differ between runs.
+type SoftwareProvider struct {
+	pcrs map[uint][]byte
+	sim  *nsmsim.Simulator
+
+	mutex   sync.Mutex
+	seed    [32]byte
+	counter uint64
+	buffer  []byte
+}
+
+var _ AttestationProvider = (*SoftwareProvider)(nil)
+
+// NewSoftwareProvider creates a SoftwareProvider with the given randomness seed and PCR values.
+// PCRs that are not provided are zero. ca may be nil, in which case Attestation returns an error.
+func NewSoftwareProvider(seed []byte, pcrs map[uint][]byte, ca *nsmsim.CA) (*SoftwareProvider, error) {
+	p := &SoftwareProvider{
+		pcrs: make(map[uint][]byte, len(pcrs)),
+		seed: sha256.Sum256(seed),
+	}
+	for index, value := range pcrs {
+		p.pcrs[index] = slices.Clone(value)
+	}
+	if ca != nil {
+		sim, err := nsmsim.New(ca, pcrs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create NSM simulator: %w", err)
+		}
+		p.sim = sim
+	}
+	return p, nil
+}
+
+// Read returns bytes from the stream sha256(seed || 0) || sha256(seed || 1) || ...
+func (p *SoftwareProvider) Read(into []byte) (int, error) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+	for n := 0; n < len(into); {
+		if len(p.buffer) == 0 {
+			block := sha256.Sum256(binary.BigEndian.AppendUint64(p.seed[:], p.counter))
+			p.counter++
+			p.buffer = block[:]
+		}
+		copied := copy(into[n:], p.buffer)
+		p.buffer = p.buffer[copied:]
+		n += copied
+	}
+	return len(into), nil
+}
+
+func (p *SoftwareProvider) PCR(index uint16) ([]byte, error) {
+	if pcr, ok := p.pcrs[uint(index)]; ok {
+		return slices.Clone(pcr), nil
+	}
+	return make([]byte, sha512.Size384), nil
+}
+
+func (p *SoftwareProvider) Attestation(publicKey, userData, nonce []byte) ([]byte, error) {
+	if p.sim == nil {
+		return nil, errors.New("attestations are not available without a simulator CA")
+	}
+	return p.sim.Attest(publicKey, userData, nonce)
+}
+
+func (p *SoftwareProvider) Close() error {
+	return nil
+}
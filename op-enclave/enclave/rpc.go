@@ -12,13 +12,42 @@ import (
 
 const Namespace = "enclave"
 
+const (
+	// CapabilityExecuteStatelessRange is advertised by enclaves that support ExecuteStatelessRange.
+	CapabilityExecuteStatelessRange = "executeStatelessRange"
+	// CapabilityAnchorProposal is advertised by enclaves that support AnchorProposal.
+	CapabilityAnchorProposal = "anchorProposal"
+)
+
+// Status describes a running enclave, so that operators and proposers can detect a dead or
+// mismatched enclave.
+type Status struct {
+	// Version is the build version of the enclave binary.
+	Version string `json:"version"`
+	// PCR0 is the enclave image measurement, or empty in local mode.
+	PCR0 hexutil.Bytes `json:"pcr0"`
+	// SignerAddress is the address of the current signer key.
+	SignerAddress common.Address `json:"signerAddress"`
+	// DecryptionKeyFingerprint is the sha256 hash of the PKIX encoded decryption public key.
+	DecryptionKeyFingerprint common.Hash `json:"decryptionKeyFingerprint"`
+	// ConfigVersions are the PerChainConfig versions the enclave can hash and execute.
+	ConfigVersions []hexutil.Uint64 `json:"configVersions"`
+	// Capabilities are the optional RPC features the enclave supports.
+	Capabilities []string `json:"capabilities"`
+	// Uptime is the number of seconds since the enclave started.
+	Uptime hexutil.Uint64 `json:"uptime"`
+}
+
 type RPC interface {
 	SignerPublicKey(ctx context.Context) (hexutil.Bytes, error)
-	SignerAttestation(ctx context.Context) (hexutil.Bytes, error)
+	SignerAttestation(ctx context.Context, nonce *hexutil.Bytes, userData *hexutil.Bytes) (hexutil.Bytes, error)
 	DecryptionPublicKey(ctx context.Context) (hexutil.Bytes, error)
-	DecryptionAttestation(ctx context.Context) (hexutil.Bytes, error)
+	DecryptionAttestation(ctx context.Context, nonce *hexutil.Bytes, userData *hexutil.Bytes) (hexutil.Bytes, error)
 	EncryptedSignerKey(ctx context.Context, attestation hexutil.Bytes) (hexutil.Bytes, error)
-	SetSignerKey(ctx context.Context, encrypted hexutil.Bytes) error
+	SetSignerKey(ctx context.Context, encrypted hexutil.Bytes, attestation hexutil.Bytes) error
+	SetTrustedPCR0s(ctx context.Context, trusted *TrustedPCR0s) error
+	Capabilities(ctx context.Context) ([]string, error)
+	Status(ctx context.Context) (*Status, error)
 	ExecuteStateless(
 		ctx context.Context,
 		config *PerChainConfig,
@@ -31,5 +60,7 @@ type RPC interface {
 		messageAccount *eth.AccountResult,
 		prevMessageAccountHash common.Hash,
 	) (*Proposal, error)
+	ExecuteStatelessRange(ctx context.Context, config *PerChainConfig, blocks []*StatelessBlock) (*Proposal, error)
 	Aggregate(ctx context.Context, configHash common.Hash, prevOutputRoot common.Hash, proposals []*Proposal) (*Proposal, error)
+	AnchorProposal(ctx context.Context, configHash common.Hash, prevOutputRoot common.Hash, proposal *Proposal, l1Headers []*types.Header) (*Proposal, error)
 }
@@ -15,6 +15,8 @@ import (
 	"io"
 	"math/big"
 	"os"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/ethereum-optimism/optimism/op-service/eth"
@@ -24,21 +26,21 @@ import (
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/hf/nitrite"
-	"github.com/hf/nsm"
-	"github.com/hf/nsm/request"
 )
 
 const (
 	// DefaultCARoots contains the PEM encoded roots for verifying Nitro
 	// Enclave attestation signatures. You can download them from
 	// https://docs.aws.amazon.com/enclaves/latest/user/verify-root.html
-	DefaultCARoots         = "UEsDBBQAAAAIALkYV1GVtvolRwIAAAkDAAAIABwAcm9vdC5wZW1VVAkAA10ekl9dHpJfdXgLAAEESHEtDwQUAAAAZZJLk6JQDIX3/IrZW10Igo2LWdwXiBoE5HXZCSq0iNgKfYVfP9guJ8tTqS85Ofn4GAszy3b+EOYHtmkTFLCX+CGBbRMWEILSfYGEjVFh+8itnoe4yKq1XC7DDNptcJ2YXJCC2+smtYfzlCEBYhewjQSospASMlwCiSJ40gE5uHAijBrAldny5PaTnRkAan77iBDUiw4B+A9heZxKkedRilflYQZdVl+meW20aayfM8tU0wTEsswdCKonUFuDAPotRUo8ag59axIE3ls84xV4D0FG6gi1mFhF4cBcQNP35GIcGCvlsV504ImXnVffRqLjxpECT2tA6Xt1AFabs7zXu33i91mvXLLaefAkveQDVgEjC/ff1g60BSqYJeFdhzFCX0i1EXYFibZdTWA57Jf0q26/vZ+Ka3BbDVlz2chy2qv8wnYK9vVgVz1OWSZpBjFi3PTtp6li8Xlk7X7vTprSUrNr+FgspofpKlGNIHe9hDA3nWGE7WPgcsEaEqdMKo2LzhtPBHkoL9YOgTEgKkZ//jRA3lLGKBRIMCwP6PCyuPQ0ZhZeWJFYoYfKlPzJMRZ6Ns9vM7feX087nQta/ALcN8CjqLCsV4yEvL2Pd6JIrRBYnEjgkfOpn/hNXi+S7qjxq4hrZxUhTTuhqavH6vbGG7HYchL5e3b82RjdVkn4vdOfLbixdD8BGSFfhv6IcbYS63Vy2M3xrfXMLs2Cz1kjF7hUvsPnRb46d0UNtwY/iftcuJtsMnckW2yGmcz/Sr+fzRz637f/A1BLAQIeAxQAAAAIALkYV1GVtvolRwIAAAkDAAAIABgAAAAAAAEAAACkgQAAAAByb290LnBlbVVUBQADXR6SX3V4CwABBEhxLQ8EFAAAAFBLBQYAAAAAAQABAE4AAACJAgAAAAA="
-	DefaultCARootsSHA256   = "8cf60e2b2efca96c6a9e71e851d00c1b6991cc09eadbe64a6a1d1b1eb9faff7c"
-	maxSequencerDriftFjord = 1800
+	DefaultCARoots       = "UEsDBBQAAAAIALkYV1GVtvolRwIAAAkDAAAIABwAcm9vdC5wZW1VVAkAA10ekl9dHpJfdXgLAAEESHEtDwQUAAAAZZJLk6JQDIX3/IrZW10Igo2LWdwXiBoE5HXZCSq0iNgKfYVfP9guJ8tTqS85Ofn4GAszy3b+EOYHtmkTFLCX+CGBbRMWEILSfYGEjVFh+8itnoe4yKq1XC7DDNptcJ2YXJCC2+smtYfzlCEBYhewjQSospASMlwCiSJ40gE5uHAijBrAldny5PaTnRkAan77iBDUiw4B+A9heZxKkedRilflYQZdVl+meW20aayfM8tU0wTEsswdCKonUFuDAPotRUo8ag59axIE3ls84xV4D0FG6gi1mFhF4cBcQNP35GIcGCvlsV504ImXnVffRqLjxpECT2tA6Xt1AFabs7zXu33i91mvXLLaefAkveQDVgEjC/ff1g60BSqYJeFdhzFCX0i1EXYFibZdTWA57Jf0q26/vZ+Ka3BbDVlz2chy2qv8wnYK9vVgVz1OWSZpBjFi3PTtp6li8Xlk7X7vTprSUrNr+FgspofpKlGNIHe9hDA3nWGE7WPgcsEaEqdMKo2LzhtPBHkoL9YOgTEgKkZ//jRA3lLGKBRIMCwP6PCyuPQ0ZhZeWJFYoYfKlPzJMRZ6Ns9vM7feX087nQta/ALcN8CjqLCsV4yEvL2Pd6JIrRBYnEjgkfOpn/hNXi+S7qjxq4hrZxUhTTuhqavH6vbGG7HYchL5e3b82RjdVkn4vdOfLbixdD8BGSFfhv6IcbYS63Vy2M3xrfXMLs2Cz1kjF7hUvsPnRb46d0UNtwY/iftcuJtsMnckW2yGmcz/Sr+fzRz637f/A1BLAQIeAxQAAAAIALkYV1GVtvolRwIAAAkDAAAIABgAAAAAAAEAAACkgQAAAAByb290LnBlbVVUBQADXR6SX3V4CwABBEhxLQ8EFAAAAFBLBQYAAAAAAQABAE4AAACJAgAAAAA="
+	DefaultCARootsSHA256 = "8cf60e2b2efca96c6a9e71e851d00c1b6991cc09eadbe64a6a1d1b1eb9faff7c"
+	// MaxAnchorHeaders bounds the L1 header chain accepted by AnchorProposal, to about a week of L1 blocks
+	MaxAnchorHeaders = 50400
 )
 
 var (
+	errLocalMode = errors.New("no Nitro Secure Module available in local mode")
+
 	defaultRoot                = createAWSNitroRoot()
 	l2ToL1MessagePasserAddress = common.HexToAddress("0x4200000000000000000000000000000000000016")
 )
@@ -71,43 +73,115 @@ func createAWSNitroRoot() *x509.CertPool {
 }
 
 type Server struct {
-	pcr0          []byte
-	signerKey     *ecdsa.PrivateKey
+	pcr0 []byte
+	// signerKey is replaced by SetSignerKey while proposals are being signed
+	signerKey     atomic.Pointer[ecdsa.PrivateKey]
 	decryptionKey *rsa.PrivateKey
+
+	// trustedPCR0s contains the keccak256 hashes of the PCR0s of other enclave images that
+	// signer keys can be transferred to and from, in addition to this enclave's own PCR0,
+	// mapped to the time they expire (or the zero time if they don't expire)
+	pcr0Mutex    sync.RWMutex
+	trustedPCR0s map[common.Hash]time.Time
+	configSigner *common.Address
+
+	nonceMutex sync.Mutex
+	usedNonces map[common.Hash]struct{}
+
+	// provider is nil in local mode, when no NSM device is available
+	provider AttestationProvider
+	roots    *x509.CertPool
+	now      func() time.Time
+
+	version   string
+	startTime time.Time
+}
+
+// Option configures optional Server behavior.
+type Option func(s *Server)
+
+// WithTrustedPCR0s allows signer keys to be transferred to and from enclaves running images with the given PCR0s.
+func WithTrustedPCR0s(pcr0s [][]byte) Option {
+	return func(s *Server) {
+		for _, pcr0 := range pcr0s {
+			s.trustedPCR0s[crypto.Keccak256Hash(pcr0)] = time.Time{}
+		}
+	}
+}
+
+// WithConfigSigner sets the address that signs the TrustedPCR0s passed to SetTrustedPCR0s.
+func WithConfigSigner(signer common.Address) Option {
+	return func(s *Server) {
+		s.configSigner = &signer
+	}
+}
+
+// WithAttestationProvider replaces the Nitro Secure Module device with another AttestationProvider,
+// such as a simulated NSM or a SoftwareProvider.
+func WithAttestationProvider(provider AttestationProvider) Option {
+	return func(s *Server) {
+		s.provider = provider
+	}
+}
+
+// WithAttestationRoots replaces the AWS Nitro root certificate used to verify attestations from
+// other enclaves. This is required when the enclaves use a simulated NSM.
+func WithAttestationRoots(roots *x509.CertPool) Option {
+	return func(s *Server) {
+		s.roots = roots
+	}
+}
+
+// WithClock replaces the clock used to check attestation certificate validity and trusted PCR0 expiry.
+func WithClock(now func() time.Time) Option {
+	return func(s *Server) {
+		s.now = now
+	}
+}
+
+// WithVersion sets the build version reported by Status.
+func WithVersion(version string) Option {
+	return func(s *Server) {
+		s.version = version
+	}
 }
 
 var _ RPC = (*Server)(nil)
 
-func NewServer() (*Server, error) {
-	var random io.Reader
+func NewServer(opts ...Option) (*Server, error) {
+	s := &Server{
+		trustedPCR0s: make(map[common.Hash]time.Time),
+		usedNonces:   make(map[common.Hash]struct{}),
+		now:          time.Now,
+		version:      "unknown",
+	}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.startTime = s.now()
+
+	var err error
 	var pcr0 []byte
-	session, err := nsm.OpenDefaultSession()
 	var signerKeyEnv string
-	if err != nil {
-		log.Warn("failed to open Nitro Secure Module session, running in local mode", "error", err)
-		random = rand.Reader
-		// only allow a signer key to be set in local mode
-		signerKeyEnv = os.Getenv("OP_ENCLAVE_SIGNER_KEY")
-	} else {
-		defer func() {
-			_ = session.Close()
-		}()
-		pcr, err := session.Send(&request.DescribePCR{
-			Index: 0,
-		})
+	if s.provider == nil {
+		// assign through a concrete variable, so a failed open leaves s.provider as a nil interface
+		provider, err := NewNitroProvider(openDefaultSession)
 		if err != nil {
-			return nil, fmt.Errorf("failed to describe PCR: %w", err)
-		}
-		if pcr.Error != "" {
-			return nil, fmt.Errorf("NSM device returned an error: %s", pcr.Error)
+			log.Warn("failed to open Nitro Secure Module session, running in local mode", "error", err)
+			// only allow a signer key to be set in local mode
+			signerKeyEnv = os.Getenv("OP_ENCLAVE_SIGNER_KEY")
+		} else {
+			s.provider = provider
 		}
-		if pcr.DescribePCR == nil || pcr.DescribePCR.Data == nil || len(pcr.DescribePCR.Data) == 0 {
-			return nil, errors.New("NSM device did not return PCR data")
+	}
+	if s.provider != nil {
+		pcr0, err = s.provider.PCR(0)
+		if err != nil {
+			return nil, err
 		}
-		pcr0 = pcr.DescribePCR.Data
-		random = session
 	}
 
+	random := s.random()
 	decryptionKey, err := rsa.GenerateKey(random, 4096)
 	if err != nil {
 		return nil, fmt.Errorf("failed to generate decryption key: %w", err)
@@ -123,69 +197,65 @@ func NewServer() (*Server, error) {
 		}
 	}
 	log.Info("Generated signer key", "address", crypto.PubkeyToAddress(signerKey.PublicKey).Hex())
-	return &Server{
-		pcr0:          pcr0,
-		signerKey:     signerKey,
-		decryptionKey: decryptionKey,
-	}, nil
+	s.pcr0 = pcr0
+	s.signerKey.Store(signerKey)
+	s.decryptionKey = decryptionKey
+	return s, nil
 }
 
 func (s *Server) SignerPublicKey(ctx context.Context) (hexutil.Bytes, error) {
-	return crypto.FromECDSAPub(&s.signerKey.PublicKey), nil
+	return crypto.FromECDSAPub(&s.signerKey.Load().PublicKey), nil
 }
 
-func (s *Server) SignerAttestation(ctx context.Context) (hexutil.Bytes, error) {
-	return s.publicKeyAttestation(ctx, s.SignerPublicKey)
+// SignerAttestation returns an attestation of the signer public key. The optional nonce and
+// user data are included in the attestation document, e.g. to prove freshness.
+func (s *Server) SignerAttestation(ctx context.Context, nonce *hexutil.Bytes, userData *hexutil.Bytes) (hexutil.Bytes, error) {
+	return s.publicKeyAttestation(ctx, s.SignerPublicKey, nonce, userData)
 }
 
 func (s *Server) DecryptionPublicKey(ctx context.Context) (hexutil.Bytes, error) {
 	return x509.MarshalPKIXPublicKey(s.decryptionKey.Public())
 }
 
-func (s *Server) DecryptionAttestation(ctx context.Context) (hexutil.Bytes, error) {
-	return s.publicKeyAttestation(ctx, s.DecryptionPublicKey)
+// DecryptionAttestation returns an attestation of the decryption public key. The optional nonce
+// and user data are included in the attestation document, e.g. to prove freshness.
+func (s *Server) DecryptionAttestation(ctx context.Context, nonce *hexutil.Bytes, userData *hexutil.Bytes) (hexutil.Bytes, error) {
+	return s.publicKeyAttestation(ctx, s.DecryptionPublicKey, nonce, userData)
 }
 
-func (s *Server) publicKeyAttestation(ctx context.Context, publicKey func(ctx context.Context) (hexutil.Bytes, error)) (hexutil.Bytes, error) {
-	session, err := nsm.OpenDefaultSession()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open session: %w", err)
+func (s *Server) publicKeyAttestation(ctx context.Context, publicKey func(ctx context.Context) (hexutil.Bytes, error), nonce *hexutil.Bytes, userData *hexutil.Bytes) (hexutil.Bytes, error) {
+	if s.provider == nil {
+		return nil, errLocalMode
 	}
-	defer func() {
-		_ = session.Close()
-	}()
 	public, err := publicKey(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get public key: %w", err)
 	}
-	res, err := session.Send(&request.Attestation{
-		PublicKey: public,
-	})
-	if err != nil {
-		return nil, fmt.Errorf("failed to get attestation: %w", err)
+	var nonceBytes, userDataBytes []byte
+	if nonce != nil {
+		nonceBytes = *nonce
 	}
-	if res.Error != "" {
-		return nil, fmt.Errorf("NSM device returned an error: %s", res.Error)
+	if userData != nil {
+		userDataBytes = *userData
 	}
-	if res.Attestation == nil || res.Attestation.Document == nil {
-		return nil, errors.New("NSM device did not return an attestation")
+	return s.provider.Attestation(public, userDataBytes, nonceBytes)
+}
+
+// random returns the provider's source of randomness, or crypto/rand in local mode.
+func (s *Server) random() io.Reader {
+	if s.provider == nil {
+		return rand.Reader
 	}
-	return res.Attestation.Document, nil
+	return s.provider
 }
 
 func (s *Server) EncryptedSignerKey(ctx context.Context, attestation hexutil.Bytes) (hexutil.Bytes, error) {
-	verification, err := nitrite.Verify(
-		attestation,
-		nitrite.VerifyOptions{
-			Roots:       defaultRoot,
-			CurrentTime: time.Now(),
-		},
-	)
+	verification, err := VerifyAttestation(attestation, VerifyOptions{Roots: s.roots, CurrentTime: s.now()})
 	if err != nil {
-		return nil, fmt.Errorf("failed to verify attestation: %w", err)
+		return nil, err
 	}
-	if !bytes.Equal(verification.Document.PCRs[0], s.pcr0) {
-		return nil, errors.New("attestation does not match PCR0")
+	if !s.isTrustedPCR0(verification.Document.PCRs[0]) {
+		return nil, errors.New("attestation PCR0 is not trusted")
 	}
 	publicKey, err := x509.ParsePKIXPublicKey(verification.Document.PublicKey)
 	if err != nil {
@@ -195,37 +265,112 @@ func (s *Server) EncryptedSignerKey(ctx context.Context, attestation hexutil.Byt
 	if !ok {
 		return nil, errors.New("public key is not RSA")
 	}
-	session, err := nsm.OpenDefaultSession()
-	if err != nil {
-		return nil, fmt.Errorf("failed to open session: %w", err)
+	if s.provider == nil {
+		return nil, errLocalMode
+	}
+	// bind the envelope to the recipient's attestation nonce if it provided one
+	nonce := verification.Document.Nonce
+	if len(nonce) == 0 {
+		nonce = make([]byte, 32)
+		if _, err = io.ReadFull(s.provider, nonce); err != nil {
+			return nil, fmt.Errorf("failed to generate nonce: %w", err)
+		}
 	}
-	defer func() {
-		_ = session.Close()
-	}()
-	ciphertext, err := rsa.EncryptPKCS1v15(session, public, crypto.FromECDSA(s.signerKey))
+	envelope, err := sealSignerKey(s.provider, public, s.signerKey.Load(), s.pcr0, nonce)
 	if err != nil {
 		return nil, fmt.Errorf("failed to encrypt key: %w", err)
 	}
-	return ciphertext, nil
+	return envelope, nil
 }
 
-func (s *Server) SetSignerKey(ctx context.Context, encrypted hexutil.Bytes) error {
-	session, err := nsm.OpenDefaultSession()
+// SetSignerKey decrypts and installs a signer key envelope produced by EncryptedSignerKey.
+// The attestation must be the sending enclave's signer attestation, from an enclave with a
+// trusted PCR0, attesting to the public key of the signer key in the envelope.
+func (s *Server) SetSignerKey(ctx context.Context, encrypted hexutil.Bytes, attestation hexutil.Bytes) error {
+	verification, err := VerifyAttestation(attestation, VerifyOptions{Roots: s.roots, CurrentTime: s.now()})
 	if err != nil {
-		return fmt.Errorf("failed to open session: %w", err)
+		return err
+	}
+	senderPCR0 := verification.Document.PCRs[0]
+	if !s.isTrustedPCR0(senderPCR0) {
+		return errors.New("attestation PCR0 is not trusted")
 	}
-	defer func() {
-		_ = session.Close()
-	}()
-	decrypted, err := rsa.DecryptPKCS1v15(session, s.decryptionKey, encrypted)
+	senderPublicKey, err := crypto.UnmarshalPubkey(verification.Document.PublicKey)
 	if err != nil {
-		return fmt.Errorf("failed to decrypt key: %w", err)
+		return fmt.Errorf("failed to parse attested signer public key: %w", err)
 	}
-	key, err := crypto.ToECDSA(decrypted)
+
+	if s.provider == nil {
+		return errLocalMode
+	}
+	envelope, err := openSignerKey(s.provider, s.decryptionKey, encrypted)
 	if err != nil {
-		return fmt.Errorf("failed to convert key: %w", err)
+		return err
+	}
+	if envelope.version != envelopeVersion0 {
+		if !envelope.signer.Equal(senderPublicKey) {
+			return errors.New("envelope is not signed by the attested signer key")
+		}
+		if !bytes.Equal(envelope.pcr0, senderPCR0) {
+			return errors.New("envelope does not match attestation PCR0")
+		}
+	}
+	if !envelope.key.PublicKey.Equal(senderPublicKey) {
+		return errors.New("signer key does not match the attested signer public key")
+	}
+	// only use up the nonce once the envelope is valid, so that a rejected envelope doesn't
+	// prevent a retry with the same decryption attestation
+	if envelope.version != envelopeVersion0 {
+		if err = s.useNonce(envelope.nonce); err != nil {
+			return err
+		}
+	}
+	s.signerKey.Store(envelope.key)
+	return nil
+}
+
+// SetTrustedPCR0s adds PCR0s of other enclave images (such as the next release) that signer keys
+// can be transferred to and from, until they expire. The list must be signed by the config signer.
+func (s *Server) SetTrustedPCR0s(ctx context.Context, trusted *TrustedPCR0s) error {
+	if s.configSigner == nil {
+		return errors.New("no config signer configured")
+	}
+	if err := trusted.Verify(*s.configSigner, s.now()); err != nil {
+		return err
+	}
+	expiry := time.Unix(int64(trusted.Expiry), 0)
+
+	s.pcr0Mutex.Lock()
+	defer s.pcr0Mutex.Unlock()
+	for _, pcr0 := range trusted.PCR0s {
+		hash := crypto.Keccak256Hash(pcr0)
+		if existing, ok := s.trustedPCR0s[hash]; ok && (existing.IsZero() || existing.After(expiry)) {
+			continue
+		}
+		s.trustedPCR0s[hash] = expiry
 	}
-	s.signerKey = key
+	return nil
+}
+
+func (s *Server) isTrustedPCR0(pcr0 []byte) bool {
+	if bytes.Equal(pcr0, s.pcr0) {
+		return true
+	}
+	s.pcr0Mutex.RLock()
+	defer s.pcr0Mutex.RUnlock()
+	expiry, ok := s.trustedPCR0s[crypto.Keccak256Hash(pcr0)]
+	return ok && (expiry.IsZero() || s.now().Before(expiry))
+}
+
+// useNonce records an envelope nonce, returning an error if it has been used before.
+func (s *Server) useNonce(nonce []byte) error {
+	s.nonceMutex.Lock()
+	defer s.nonceMutex.Unlock()
+	hash := crypto.Keccak256Hash(nonce)
+	if _, ok := s.usedNonces[hash]; ok {
+		return errors.New("envelope nonce has already been used")
+	}
+	s.usedNonces[hash] = struct{}{}
 	return nil
 }
 
@@ -236,6 +381,49 @@ type Proposal struct {
 	L2BlockNumber *hexutil.Big
 }
 
+// StatelessBlock contains the inputs required to statelessly execute a single L2 block.
+type StatelessBlock struct {
+	L1Origin               *types.Header
+	L1Receipts             types.Receipts
+	PreviousBlockTxs       []hexutil.Bytes
+	BlockHeader            *types.Header
+	SequencedTxs           []hexutil.Bytes
+	Witness                *stateless.ExecutionWitness
+	MessageAccount         *eth.AccountResult
+	PrevMessageAccountHash common.Hash
+}
+
+func (s *Server) Capabilities(ctx context.Context) ([]string, error) {
+	return []string{
+		CapabilityExecuteStatelessRange,
+		CapabilityAnchorProposal,
+	}, nil
+}
+
+func (s *Server) Status(ctx context.Context) (*Status, error) {
+	decryptionPublicKey, err := s.DecryptionPublicKey(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get decryption public key: %w", err)
+	}
+	capabilities, err := s.Capabilities(ctx)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]hexutil.Uint64, len(configVersions))
+	for i, v := range configVersions {
+		versions[i] = hexutil.Uint64(v)
+	}
+	return &Status{
+		Version:                  s.version,
+		PCR0:                     s.pcr0,
+		SignerAddress:            crypto.PubkeyToAddress(s.signerKey.Load().PublicKey),
+		DecryptionKeyFingerprint: sha256.Sum256(decryptionPublicKey),
+		ConfigVersions:           versions,
+		Capabilities:             capabilities,
+		Uptime:                   hexutil.Uint64(s.now().Sub(s.startTime) / time.Second),
+	}, nil
+}
+
 func (s *Server) ExecuteStateless(
 	ctx context.Context,
 	cfg *PerChainConfig,
@@ -248,52 +436,96 @@ func (s *Server) ExecuteStateless(
 	messageAccount *eth.AccountResult,
 	prevMessageAccountHash common.Hash,
 ) (*Proposal, error) {
-	codes, err := transformMap(witness.Codes)
+	if err := cfg.Check(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+	config := NewChainConfig(cfg)
+	prevOutputRoot, outputRoot, err := executeStateless(ctx, config, &StatelessBlock{
+		L1Origin:               l1Origin,
+		L1Receipts:             l1Receipts,
+		PreviousBlockTxs:       previousBlockTxs,
+		BlockHeader:            blockHeader,
+		SequencedTxs:           sequencedTxs,
+		Witness:                witness,
+		MessageAccount:         messageAccount,
+		PrevMessageAccountHash: prevMessageAccountHash,
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode witness: %w", err)
+		return nil, err
+	}
+	return signProposal(s.signerKey.Load(), config.Hash(), l1Origin.Hash(), blockHeader.Number, prevOutputRoot, outputRoot)
+}
+
+// ExecuteStatelessRange executes a contiguous range of L2 blocks in order, and returns
+// a single proposal that transitions from the output root before the first block to
+// the output root after the last block.
+func (s *Server) ExecuteStatelessRange(ctx context.Context, cfg *PerChainConfig, blocks []*StatelessBlock) (*Proposal, error) {
+	if len(blocks) == 0 {
+		return nil, errors.New("no blocks")
+	}
+	if err := cfg.Check(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
+	config := NewChainConfig(cfg)
+	var prevOutputRoot, outputRoot common.Hash
+	for i, block := range blocks {
+		blockPrevOutputRoot, blockOutputRoot, err := executeStateless(ctx, config, block)
+		if err != nil {
+			return nil, fmt.Errorf("failed to execute block %d: %w", i, err)
+		}
+		if i == 0 {
+			prevOutputRoot = blockPrevOutputRoot
+		} else if blockPrevOutputRoot != outputRoot {
+			return nil, fmt.Errorf("block %d is not contiguous with the previous block", i)
+		}
+		outputRoot = blockOutputRoot
 	}
-	state, err := transformMap(witness.State)
+
+	last := blocks[len(blocks)-1]
+	return signProposal(s.signerKey.Load(), config.Hash(), last.L1Origin.Hash(), last.BlockHeader.Number, prevOutputRoot, outputRoot)
+}
+
+func executeStateless(ctx context.Context, config *ChainConfig, block *StatelessBlock) (prevOutputRoot common.Hash, outputRoot common.Hash, err error) {
+	codes, err := transformMap(block.Witness.Codes)
 	if err != nil {
-		return nil, fmt.Errorf("failed to decode witness: %w", err)
+		return common.Hash{}, common.Hash{}, fmt.Errorf("failed to decode witness: %w", err)
+	}
+	state, err := transformMap(block.Witness.State)
+	if err != nil {
+		return common.Hash{}, common.Hash{}, fmt.Errorf("failed to decode witness: %w", err)
 	}
 	w := &stateless.Witness{
-		Headers: witness.Headers,
+		Headers: block.Witness.Headers,
 		Codes:   codes,
 		State:   state,
 	}
 
-	config := NewChainConfig(cfg)
-	l1OriginHash := l1Origin.Hash()
 	previousBlockHeader := w.Headers[0]
 
 	err = ExecuteStateless(ctx, config.ChainConfig, config.ToRollupConfig(),
-		l1Origin, l1Receipts, previousBlockTxs, blockHeader, sequencedTxs, w, messageAccount)
+		block.L1Origin, block.L1Receipts, block.PreviousBlockTxs, block.BlockHeader, block.SequencedTxs, w, block.MessageAccount,
+		config.Version >= version1)
 	if err != nil {
-		return nil, err
+		return common.Hash{}, common.Hash{}, err
 	}
 
-	prevOutputRoot := OutputRootV0(previousBlockHeader, prevMessageAccountHash)
-	outputRoot := OutputRootV0(blockHeader, messageAccount.StorageHash)
-	configHash := config.Hash()
-	l2BlockNumber := common.BytesToHash(blockHeader.Number.Bytes())
+	prevOutputRoot = OutputRootV0(previousBlockHeader, block.PrevMessageAccountHash)
+	outputRoot = OutputRootV0(block.BlockHeader, block.MessageAccount.StorageHash)
+	return prevOutputRoot, outputRoot, nil
+}
 
-	data := append(configHash[:], l1OriginHash[:]...)
-	data = append(data, l2BlockNumber[:]...)
-	data = append(data, prevOutputRoot[:]...)
-	data = append(data, outputRoot[:]...)
-	sig, err := crypto.Sign(crypto.Keccak256(data), s.signerKey)
+func signProposal(signerKey *ecdsa.PrivateKey, configHash common.Hash, l1OriginHash common.Hash, l2BlockNumber *big.Int, prevOutputRoot common.Hash, outputRoot common.Hash) (*Proposal, error) {
+	hash := ProposalHash(configHash, l1OriginHash, l2BlockNumber, prevOutputRoot, outputRoot)
+	sig, err := crypto.Sign(hash[:], signerKey)
 	if err != nil {
 		return nil, fmt.Errorf("failed to sign: %w", err)
 	}
-
-	if err != nil {
-		return nil, err
-	}
 	return &Proposal{
 		OutputRoot:    outputRoot,
 		Signature:     sig,
 		L1OriginHash:  l1OriginHash,
-		L2BlockNumber: (*hexutil.Big)(blockHeader.Number),
+		L2BlockNumber: (*hexutil.Big)(l2BlockNumber),
 	}, nil
 }
 
@@ -305,37 +537,64 @@ func (s *Server) Aggregate(ctx context.Context, configHash common.Hash, prevOutp
 		return proposals[0], nil
 	}
 
+	// verify and sign with the same key, even if it is replaced concurrently
+	signerKey := s.signerKey.Load()
 	outputRoot := prevOutputRoot
 	var l1OriginHash common.Hash
-	var l2BlockNumber common.Hash
+	var l2BlockNumber *big.Int
 	for _, p := range proposals {
 		l1OriginHash = p.L1OriginHash
-		l2BlockNumber = common.BytesToHash(p.L2BlockNumber.ToInt().Bytes())
-		data := append(configHash[:], l1OriginHash[:]...)
-		data = append(data, l2BlockNumber[:]...)
-		data = append(data, outputRoot[:]...)
-		data = append(data, p.OutputRoot[:]...)
-		if !crypto.VerifySignature(crypto.FromECDSAPub(&s.signerKey.PublicKey), crypto.Keccak256(data), p.Signature[:64]) {
+		l2BlockNumber = p.L2BlockNumber.ToInt()
+		hash := ProposalHash(configHash, l1OriginHash, l2BlockNumber, outputRoot, p.OutputRoot)
+		if !crypto.VerifySignature(crypto.FromECDSAPub(&signerKey.PublicKey), hash[:], p.Signature[:64]) {
 			return nil, errors.New("invalid signature")
 		}
 		outputRoot = p.OutputRoot
 	}
 
+	return signProposal(signerKey, configHash, l1OriginHash, l2BlockNumber, prevOutputRoot, outputRoot)
+}
+
+// AnchorProposal re-signs a proposal against a more recent L1 block, so that it can still be
+// submitted once its L1 origin is older than the 256 blocks that the BLOCKHASH opcode can
+// access. l1Headers must be a contiguous chain of L1 headers from the child of the proposal's L1
+// origin to the new anchor block. Since the anchor is a descendant of the L1 origin, the
+// on-chain check of the anchor's hash also commits to the L1 origin.
+func (s *Server) AnchorProposal(ctx context.Context, configHash common.Hash, prevOutputRoot common.Hash, proposal *Proposal, l1Headers []*types.Header) (*Proposal, error) {
+	if len(l1Headers) == 0 {
+		return nil, errors.New("no L1 headers")
+	}
+	if len(l1Headers) > MaxAnchorHeaders {
+		return nil, fmt.Errorf("too many L1 headers: %d > %d", len(l1Headers), MaxAnchorHeaders)
+	}
+
+	signerKey := s.signerKey.Load()
+	l2BlockNumber := proposal.L2BlockNumber.ToInt()
+	hash := ProposalHash(configHash, proposal.L1OriginHash, l2BlockNumber, prevOutputRoot, proposal.OutputRoot)
+	if len(proposal.Signature) < 64 || !crypto.VerifySignature(crypto.FromECDSAPub(&signerKey.PublicKey), hash[:], proposal.Signature[:64]) {
+		return nil, errors.New("invalid signature")
+	}
+
+	parentHash := proposal.L1OriginHash
+	for i, header := range l1Headers {
+		if header.ParentHash != parentHash {
+			return nil, fmt.Errorf("L1 header %d is not a child of the previous block", i)
+		}
+		parentHash = header.Hash()
+	}
+
+	return signProposal(signerKey, configHash, parentHash, l2BlockNumber, prevOutputRoot, proposal.OutputRoot)
+}
+
+// ProposalHash returns the digest signed by the enclave for a proposal. It matches the
+// digest recovered in OutputOracle.proposeL2Output.
+func ProposalHash(configHash common.Hash, l1OriginHash common.Hash, l2BlockNumber *big.Int, prevOutputRoot common.Hash, outputRoot common.Hash) common.Hash {
+	number := common.BigToHash(l2BlockNumber)
 	data := append(configHash[:], l1OriginHash[:]...)
-	data = append(data, l2BlockNumber[:]...)
+	data = append(data, number[:]...)
 	data = append(data, prevOutputRoot[:]...)
 	data = append(data, outputRoot[:]...)
-	sig, err := crypto.Sign(crypto.Keccak256(data), s.signerKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to sign: %w", err)
-	}
-
-	return &Proposal{
-		OutputRoot:    outputRoot,
-		Signature:     sig,
-		L1OriginHash:  l1OriginHash,
-		L2BlockNumber: (*hexutil.Big)(new(big.Int).SetBytes(l2BlockNumber[:])),
-	}, nil
+	return crypto.Keccak256Hash(data)
 }
 
 func OutputRootV0(header *types.Header, storageRoot common.Hash) common.Hash {
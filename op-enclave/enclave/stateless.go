@@ -29,6 +29,7 @@ func ExecuteStateless(
 	sequencedTxs []hexutil.Bytes,
 	witness *stateless.Witness,
 	messageAccount *eth.AccountResult,
+	checkTimestamp bool,
 ) error {
 	l1OriginHash := l1Origin.Hash()
 	computed := types.DeriveSha(l1Receipts, trie.NewStackTrie(nil))
@@ -43,7 +44,8 @@ func ExecuteStateless(
 	}
 
 	// block must only contain deposit transactions if it is outside the sequencer drift
-	if len(sequencedTxs) > 0 && blockHeader.Time > l1Origin.Time+maxSequencerDriftFjord {
+	maxSequencerDrift := rollup.NewChainSpec(rollupConfig).MaxSequencerDrift(l1Origin.Time)
+	if len(sequencedTxs) > 0 && blockHeader.Time > l1Origin.Time+maxSequencerDrift {
 		return errors.New("l1 origin is too old")
 	}
 
@@ -90,6 +92,12 @@ func ExecuteStateless(
 	if err != nil {
 		return fmt.Errorf("failed to prepare payload attributes: %w", err)
 	}
+	// the derived timestamp selects the fork upgrade transactions, and follows from the config's
+	// block time; version 0 configs have a fixed block time, so chains with a different block time
+	// are only checked from version 1 onwards
+	if checkTimestamp && blockHeader.Time != uint64(payload.Timestamp) {
+		return fmt.Errorf("invalid block timestamp: %d, expected %d", blockHeader.Time, uint64(payload.Timestamp))
+	}
 
 	// sequencer cannot include manual deposit transactions; otherwise it could mint funds arbitrarily
 	txs, err := unmarshalTxs(sequencedTxs)
@@ -0,0 +1,58 @@
+package enclave
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TransferSignerKey moves the signer key from one enclave to another: the destination enclave
+// attests to its decryption key, the source enclave encrypts its signer key to that attested key,
+// and the destination enclave decrypts and installs it after verifying the source enclave's
+// signer attestation. Returns the address of the transferred
+// signer key, once both enclaves report the same signer public key.
+func TransferSignerKey(ctx context.Context, from RPC, to RPC) (common.Address, error) {
+	// a fresh nonce in the decryption attestation is bound into the encrypted envelope,
+	// which the destination enclave only accepts once
+	nonce := make(hexutil.Bytes, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return common.Address{}, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	attestation, err := to.DecryptionAttestation(ctx, &nonce, nil)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get decryption attestation: %w", err)
+	}
+	encrypted, err := from.EncryptedSignerKey(ctx, attestation)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get encrypted signer key: %w", err)
+	}
+	signerAttestation, err := from.SignerAttestation(ctx, nil, nil)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get signer attestation: %w", err)
+	}
+	if err = to.SetSignerKey(ctx, encrypted, signerAttestation); err != nil {
+		return common.Address{}, fmt.Errorf("failed to set signer key: %w", err)
+	}
+
+	fromPublicKey, err := from.SignerPublicKey(ctx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get source signer public key: %w", err)
+	}
+	toPublicKey, err := to.SignerPublicKey(ctx)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to get destination signer public key: %w", err)
+	}
+	if !bytes.Equal(fromPublicKey, toPublicKey) {
+		return common.Address{}, fmt.Errorf("signer public keys do not match after transfer: %s != %s", fromPublicKey, toPublicKey)
+	}
+	pub, err := crypto.UnmarshalPubkey(toPublicKey)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to parse signer public key: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
@@ -0,0 +1,142 @@
+package enclave
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+
+	"github.com/base/op-enclave/op-enclave/nsmsim"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer creates a server backed by a SoftwareProvider with the given PCR0, whose
+// attestations are signed by the simulator CA.
+func newTestServer(t *testing.T, ca *nsmsim.CA, seed string, pcr0 []byte, opts ...Option) *Server {
+	t.Helper()
+	provider, err := NewSoftwareProvider([]byte(seed), map[uint][]byte{0: pcr0}, ca)
+	require.NoError(t, err)
+	opts = append([]Option{WithAttestationProvider(provider), WithAttestationRoots(ca.Roots())}, opts...)
+	s, err := NewServer(opts...)
+	require.NoError(t, err)
+	return s
+}
+
+func TestTransferSignerKey(t *testing.T) {
+	ctx := context.Background()
+	ca, err := nsmsim.NewCA()
+	require.NoError(t, err)
+	pcr0 := bytes.Repeat([]byte{0x01}, 48)
+	from := newTestServer(t, ca, "from", pcr0)
+	to := newTestServer(t, ca, "to", pcr0)
+
+	fromKey, err := from.SignerPublicKey(ctx)
+	require.NoError(t, err)
+	toKey, err := to.SignerPublicKey(ctx)
+	require.NoError(t, err)
+	require.NotEqual(t, fromKey, toKey)
+
+	address, err := TransferSignerKey(ctx, from, to)
+	require.NoError(t, err)
+	pub, err := crypto.UnmarshalPubkey(fromKey)
+	require.NoError(t, err)
+	require.Equal(t, crypto.PubkeyToAddress(*pub), address)
+
+	status, err := to.Status(ctx)
+	require.NoError(t, err)
+	require.Equal(t, address, status.SignerAddress)
+}
+
+func TestSetSignerKey(t *testing.T) {
+	ctx := context.Background()
+	ca, err := nsmsim.NewCA()
+	require.NoError(t, err)
+	pcr0 := bytes.Repeat([]byte{0x01}, 48)
+	from := newTestServer(t, ca, "from", pcr0)
+	to := newTestServer(t, ca, "to", pcr0)
+
+	nonce := hexutil.Bytes("nonce")
+	decryptionAttestation, err := to.DecryptionAttestation(ctx, &nonce, nil)
+	require.NoError(t, err)
+	encrypted, err := from.EncryptedSignerKey(ctx, decryptionAttestation)
+	require.NoError(t, err)
+	signerAttestation, err := from.SignerAttestation(ctx, nil, nil)
+	require.NoError(t, err)
+
+	t.Run("wrong sender attestation", func(t *testing.T) {
+		// the destination's own signer attestation doesn't attest to the key in the envelope
+		attestation, err := to.SignerAttestation(ctx, nil, nil)
+		require.NoError(t, err)
+		require.ErrorContains(t, to.SetSignerKey(ctx, encrypted, attestation), "not signed by the attested signer key")
+	})
+
+	t.Run("untrusted CA", func(t *testing.T) {
+		other, err := nsmsim.NewCA()
+		require.NoError(t, err)
+		sender := newTestServer(t, other, "sender", pcr0)
+		attestation, err := sender.SignerAttestation(ctx, nil, nil)
+		require.NoError(t, err)
+		require.ErrorContains(t, to.SetSignerKey(ctx, encrypted, attestation), "failed to verify attestation")
+	})
+
+	require.NoError(t, to.SetSignerKey(ctx, encrypted, signerAttestation))
+	fromKey, err := from.SignerPublicKey(ctx)
+	require.NoError(t, err)
+	toKey, err := to.SignerPublicKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, fromKey, toKey)
+
+	// envelopes can only be installed once
+	require.ErrorContains(t, to.SetSignerKey(ctx, encrypted, signerAttestation), "already been used")
+}
+
+func TestTransferSignerKeyUntrustedPCR0(t *testing.T) {
+	ctx := context.Background()
+	ca, err := nsmsim.NewCA()
+	require.NoError(t, err)
+	pcr0 := bytes.Repeat([]byte{0x01}, 48)
+	otherPCR0 := bytes.Repeat([]byte{0x02}, 48)
+	from := newTestServer(t, ca, "from", pcr0)
+	to := newTestServer(t, ca, "to", otherPCR0)
+
+	_, err = TransferSignerKey(ctx, from, to)
+	require.ErrorContains(t, err, "PCR0 is not trusted")
+
+	// trusting each other's image allows the transfer in both directions
+	from = newTestServer(t, ca, "from", pcr0, WithTrustedPCR0s([][]byte{otherPCR0}))
+	to = newTestServer(t, ca, "to", otherPCR0, WithTrustedPCR0s([][]byte{pcr0}))
+	_, err = TransferSignerKey(ctx, from, to)
+	require.NoError(t, err)
+}
+
+func TestSetSignerKeyLegacyEnvelope(t *testing.T) {
+	ctx := context.Background()
+	ca, err := nsmsim.NewCA()
+	require.NoError(t, err)
+	pcr0 := bytes.Repeat([]byte{0x01}, 48)
+	from := newTestServer(t, ca, "from", pcr0)
+	to := newTestServer(t, ca, "to", pcr0)
+
+	// enclaves that predate versioned envelopes encrypt the raw key with PKCS#1 v1.5
+	legacy, err := rsa.EncryptPKCS1v15(rand.Reader, &to.decryptionKey.PublicKey, crypto.FromECDSA(from.signerKey.Load()))
+	require.NoError(t, err)
+	signerAttestation, err := from.SignerAttestation(ctx, nil, nil)
+	require.NoError(t, err)
+
+	// a legacy envelope of a different key is rejected
+	other, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	wrong, err := rsa.EncryptPKCS1v15(rand.Reader, &to.decryptionKey.PublicKey, crypto.FromECDSA(other))
+	require.NoError(t, err)
+	require.ErrorContains(t, to.SetSignerKey(ctx, wrong, signerAttestation), "does not match the attested signer public key")
+
+	require.NoError(t, to.SetSignerKey(ctx, legacy, signerAttestation))
+	fromKey, err := from.SignerPublicKey(ctx)
+	require.NoError(t, err)
+	toKey, err := to.SignerPublicKey(ctx)
+	require.NoError(t, err)
+	require.Equal(t, fromKey, toKey)
+}
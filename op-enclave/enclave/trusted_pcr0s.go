@@ -0,0 +1,63 @@
+package enclave
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+)
+
+// TrustedPCR0s is a list of PCR0s of enclave images that signer keys may be transferred to
+// and from, such as the successor of the current enclave image. It must be signed by the
+// config signer the enclave was started with.
+type TrustedPCR0s struct {
+	PCR0s []hexutil.Bytes `json:"pcr0s"`
+	// Expiry is the unix timestamp after which the PCR0s are no longer trusted.
+	Expiry    uint64        `json:"expiry"`
+	Signature hexutil.Bytes `json:"signature"`
+}
+
+// Hash returns the digest signed by the config signer:
+// keccak256(keccak256(pcr0_0) || ... || keccak256(pcr0_n) || uint64(expiry)).
+func (t *TrustedPCR0s) Hash() common.Hash {
+	var data []byte
+	for _, pcr0 := range t.PCR0s {
+		data = append(data, crypto.Keccak256(pcr0)...)
+	}
+	data = binary.BigEndian.AppendUint64(data, t.Expiry)
+	return crypto.Keccak256Hash(data)
+}
+
+// Signer recovers the address that signed the list.
+func (t *TrustedPCR0s) Signer() (common.Address, error) {
+	hash := t.Hash()
+	sig := make([]byte, len(t.Signature))
+	copy(sig, t.Signature)
+	if len(sig) == crypto.SignatureLength && sig[crypto.RecoveryIDOffset] >= 27 {
+		sig[crypto.RecoveryIDOffset] -= 27
+	}
+	pub, err := crypto.SigToPub(hash[:], sig)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover signer: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// Verify checks that the list is signed by the given signer, and has not expired.
+func (t *TrustedPCR0s) Verify(signer common.Address, now time.Time) error {
+	recovered, err := t.Signer()
+	if err != nil {
+		return err
+	}
+	if recovered != signer {
+		return fmt.Errorf("trusted PCR0s are signed by %s, expected %s", recovered, signer)
+	}
+	if uint64(now.Unix()) >= t.Expiry {
+		return errors.New("trusted PCR0s have expired")
+	}
+	return nil
+}
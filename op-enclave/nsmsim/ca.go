@@ -0,0 +1,160 @@
+package nsmsim
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// CA is the certificate authority that signs the simulator's attestation documents.
+// It replaces the AWS Nitro root certificate when verifying simulated attestations.
+type CA struct {
+	Certificate *x509.Certificate
+	key         *ecdsa.PrivateKey
+}
+
+// NewCA generates a new self-signed P-384 certificate authority.
+func NewCA() (*CA, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate CA key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "nsm-simulator"},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.AddDate(10, 0, 0),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign | x509.KeyUsageDigitalSignature,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		SignatureAlgorithm:    x509.ECDSAWithSHA384,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create CA certificate: %w", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+	}
+	return &CA{
+		Certificate: cert,
+		key:         key,
+	}, nil
+}
+
+// ParseCA parses a CA from PEM data containing a CERTIFICATE block and an EC PRIVATE KEY
+// (or PKCS#8 PRIVATE KEY) block, as produced by MarshalPEM.
+func ParseCA(data []byte) (*CA, error) {
+	ca := &CA{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		switch block.Type {
+		case "CERTIFICATE":
+			cert, err := x509.ParseCertificate(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse CA certificate: %w", err)
+			}
+			ca.Certificate = cert
+		case "EC PRIVATE KEY":
+			key, err := x509.ParseECPrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse CA key: %w", err)
+			}
+			ca.key = key
+		case "PRIVATE KEY":
+			key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+			if err != nil {
+				return nil, fmt.Errorf("failed to parse CA key: %w", err)
+			}
+			ecKey, ok := key.(*ecdsa.PrivateKey)
+			if !ok {
+				return nil, errors.New("CA key is not ECDSA")
+			}
+			ca.key = ecKey
+		}
+	}
+	if ca.Certificate == nil {
+		return nil, errors.New("missing CA certificate")
+	}
+	if ca.key == nil {
+		return nil, errors.New("missing CA key")
+	}
+	if !ca.key.PublicKey.Equal(ca.Certificate.PublicKey) {
+		return nil, errors.New("CA key does not match certificate")
+	}
+	return ca, nil
+}
+
+// MarshalPEM encodes the CA certificate and private key as PEM.
+func (c *CA) MarshalPEM() ([]byte, error) {
+	key, err := x509.MarshalECPrivateKey(c.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal CA key: %w", err)
+	}
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Certificate.Raw})
+	return append(data, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: key})...), nil
+}
+
+// CertificatePEM encodes the CA certificate as PEM, for distribution to verifiers.
+func (c *CA) CertificatePEM() []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Certificate.Raw})
+}
+
+// Roots returns a certificate pool containing the CA certificate, for use with nitrite.Verify.
+func (c *CA) Roots() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(c.Certificate)
+	return pool
+}
+
+// issue creates a short-lived P-384 signing certificate for an attestation document,
+// like the per-enclave certificates issued by AWS.
+func (c *CA) issue(moduleID string) (*ecdsa.PrivateKey, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, nil, err
+	}
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:       serial,
+		Subject:            pkix.Name{CommonName: moduleID},
+		NotBefore:          now.Add(-time.Minute),
+		NotAfter:           now.Add(3 * time.Hour),
+		KeyUsage:           x509.KeyUsageDigitalSignature,
+		SignatureAlgorithm: x509.ECDSAWithSHA384,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, c.Certificate, &key.PublicKey, c.key)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create signing certificate: %w", err)
+	}
+	return key, der, nil
+}
+
+func randomSerial() (*big.Int, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %w", err)
+	}
+	return serial, nil
+}
@@ -0,0 +1,301 @@
+// Package nsmsim implements a software simulator of the AWS Nitro Secure Module (NSM).
+//
+// The simulator answers the same requests as the NSM device, and produces COSE_Sign1
+// attestation documents that pass nitrite.Verify when the simulator's CA is used as
+// the root of trust. It provides no security guarantees, and exists so that attestation
+// and signer key transfer can be exercised outside of a Nitro Enclave.
+package nsmsim
+
+import (
+	"crypto/ecdsa"
+	"crypto/rand"
+	"crypto/sha512"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/fxamacker/cbor/v2"
+	"github.com/hf/nsm/request"
+	"github.com/hf/nsm/response"
+)
+
+const (
+	pcrCount  = 32
+	pcrLength = sha512.Size384
+
+	maxPublicKeyLength = 1024
+	maxUserDataLength  = 512
+	maxNonceLength     = 512
+	maxRandomLength    = 256
+
+	// coseAlgorithmES384 is the COSE algorithm identifier for ECDSA with SHA-384.
+	coseAlgorithmES384 = -35
+	coseHeaderAlg      = 1
+)
+
+// Simulator is a simulated NSM device. It is safe for concurrent use.
+type Simulator struct {
+	ca       *CA
+	moduleID string
+
+	mutex  sync.Mutex
+	pcrs   [pcrCount][]byte
+	locked [pcrCount]bool
+}
+
+// New creates a simulator whose attestations are signed by the given CA. PCRs that are not
+// provided are zero. The provided PCRs are locked, like the image PCRs of a real enclave.
+func New(ca *CA, pcrs map[uint][]byte) (*Simulator, error) {
+	s := &Simulator{
+		ca:       ca,
+		moduleID: "i-00000000000000000-enc0000000000000000",
+	}
+	for i := range s.pcrs {
+		s.pcrs[i] = make([]byte, pcrLength)
+	}
+	for index, value := range pcrs {
+		if index >= pcrCount {
+			return nil, fmt.Errorf("invalid PCR index: %d", index)
+		}
+		if len(value) != pcrLength {
+			return nil, fmt.Errorf("invalid length of PCR%d: %d", index, len(value))
+		}
+		s.pcrs[index] = slices.Clone(value)
+		s.locked[index] = true
+	}
+	return s, nil
+}
+
+// Open opens a session with the simulator. The returned session has the same methods
+// as *nsm.Session.
+func (s *Simulator) Open() (*Session, error) {
+	return &Session{sim: s}, nil
+}
+
+// Session is a session with a simulated NSM device.
+type Session struct {
+	sim *Simulator
+}
+
+// Send sends a request to the simulator and returns its response.
+func (s *Session) Send(req request.Request) (response.Response, error) {
+	if s.sim == nil {
+		return response.Response{}, errors.New("session is closed")
+	}
+	return s.sim.handle(req), nil
+}
+
+// Read reads entropy from the simulator, which uses crypto/rand.
+func (s *Session) Read(into []byte) (int, error) {
+	if s.sim == nil {
+		return 0, errors.New("session is closed")
+	}
+	return rand.Read(into)
+}
+
+// Close closes the session.
+func (s *Session) Close() error {
+	s.sim = nil
+	return nil
+}
+
+func (s *Simulator) handle(req request.Request) response.Response {
+	switch r := req.(type) {
+	case *request.DescribePCR:
+		return s.describePCR(r.Index)
+	case *request.ExtendPCR:
+		return s.extendPCR(r.Index, r.Data)
+	case *request.LockPCR:
+		return s.lockPCRs(r.Index, r.Index+1)
+	case *request.LockPCRs:
+		return s.lockPCRs(0, r.Range)
+	case *request.DescribeNSM:
+		return s.describeNSM()
+	case *request.Attestation:
+		return s.attestation(r)
+	case *request.GetRandom:
+		random := make([]byte, maxRandomLength)
+		if _, err := rand.Read(random); err != nil {
+			return errorResponse(response.ECInternalError)
+		}
+		return response.Response{GetRandom: &response.GetRandom{Random: random}}
+	default:
+		return errorResponse(response.ECInvalidOperation)
+	}
+}
+
+func (s *Simulator) describePCR(index uint16) response.Response {
+	if index >= pcrCount {
+		return errorResponse(response.ECInvalidArgument)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	return response.Response{DescribePCR: &response.DescribePCR{
+		Lock: s.locked[index],
+		Data: slices.Clone(s.pcrs[index]),
+	}}
+}
+
+func (s *Simulator) extendPCR(index uint16, data []byte) response.Response {
+	if index >= pcrCount {
+		return errorResponse(response.ECInvalidArgument)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	if s.locked[index] {
+		return errorResponse(response.ECReadOnlyIndex)
+	}
+	h := sha512.New384()
+	h.Write(s.pcrs[index])
+	h.Write(data)
+	s.pcrs[index] = h.Sum(nil)
+	return response.Response{ExtendPCR: &response.ExtendPCR{Data: slices.Clone(s.pcrs[index])}}
+}
+
+func (s *Simulator) lockPCRs(from, to uint16) response.Response {
+	if to > pcrCount || from >= to {
+		return errorResponse(response.ECInvalidArgument)
+	}
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for i := from; i < to; i++ {
+		s.locked[i] = true
+	}
+	if to == from+1 {
+		return response.Response{LockPCR: &response.LockPCR{}}
+	}
+	return response.Response{LockPCRs: &response.LockPCRs{}}
+}
+
+func (s *Simulator) describeNSM() response.Response {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	var locked []uint16
+	for i, l := range s.locked {
+		if l {
+			locked = append(locked, uint16(i))
+		}
+	}
+	return response.Response{DescribeNSM: &response.DescribeNSM{
+		VersionMajor: 1,
+		ModuleID:     s.moduleID,
+		MaxPCRs:      pcrCount,
+		LockedPCRs:   locked,
+		Digest:       response.DigestSHA384,
+	}}
+}
+
+// document is the attestation document payload, with the field names used by the NSM.
+type document struct {
+	ModuleID    string          `cbor:"module_id"`
+	Digest      string          `cbor:"digest"`
+	Timestamp   uint64          `cbor:"timestamp"`
+	PCRs        map[uint][]byte `cbor:"pcrs"`
+	Certificate []byte          `cbor:"certificate"`
+	CABundle    [][]byte        `cbor:"cabundle"`
+	PublicKey   []byte          `cbor:"public_key,omitempty"`
+	UserData    []byte          `cbor:"user_data,omitempty"`
+	Nonce       []byte          `cbor:"nonce,omitempty"`
+}
+
+type coseSign1 struct {
+	_ struct{} `cbor:",toarray"`
+
+	Protected   []byte
+	Unprotected map[int]interface{}
+	Payload     []byte
+	Signature   []byte
+}
+
+type coseSigStructure struct {
+	_ struct{} `cbor:",toarray"`
+
+	Context     string
+	Protected   []byte
+	ExternalAAD []byte
+	Payload     []byte
+}
+
+func (s *Simulator) attestation(req *request.Attestation) response.Response {
+	if len(req.PublicKey) > maxPublicKeyLength || len(req.UserData) > maxUserDataLength || len(req.Nonce) > maxNonceLength {
+		return errorResponse(response.ECInputTooLarge)
+	}
+	doc, err := s.Attest(req.PublicKey, req.UserData, req.Nonce)
+	if err != nil {
+		return errorResponse(response.ECInternalError)
+	}
+	return response.Response{Attestation: &response.Attestation{Document: doc}}
+}
+
+// Attest creates a COSE_Sign1 attestation document containing the current PCRs and the
+// given public key, user data and nonce, which may be empty.
+func (s *Simulator) Attest(publicKey, userData, nonce []byte) ([]byte, error) {
+	key, cert, err := s.ca.issue(s.moduleID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mutex.Lock()
+	pcrs := make(map[uint][]byte, pcrCount)
+	for i, pcr := range s.pcrs {
+		pcrs[uint(i)] = slices.Clone(pcr)
+	}
+	s.mutex.Unlock()
+
+	payload, err := cbor.Marshal(&document{
+		ModuleID:    s.moduleID,
+		Digest:      string(response.DigestSHA384),
+		Timestamp:   uint64(time.Now().UnixMilli()),
+		PCRs:        pcrs,
+		Certificate: cert,
+		CABundle:    [][]byte{s.ca.Certificate.Raw},
+		PublicKey:   publicKey,
+		UserData:    userData,
+		Nonce:       nonce,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode attestation document: %w", err)
+	}
+	protected, err := cbor.Marshal(map[int]int{coseHeaderAlg: coseAlgorithmES384})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode protected header: %w", err)
+	}
+	sigStructure, err := cbor.Marshal(&coseSigStructure{
+		Context:     "Signature1",
+		Protected:   protected,
+		ExternalAAD: []byte{},
+		Payload:     payload,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode signature structure: %w", err)
+	}
+	signature, err := sign(key, sigStructure)
+	if err != nil {
+		return nil, err
+	}
+	return cbor.Marshal(&coseSign1{
+		Protected:   protected,
+		Unprotected: map[int]interface{}{},
+		Payload:     payload,
+		Signature:   signature,
+	})
+}
+
+// sign creates a COSE ES384 signature, which is the fixed-size concatenation of r and s.
+func sign(key *ecdsa.PrivateKey, data []byte) ([]byte, error) {
+	digest := sha512.Sum384(data)
+	r, s, err := ecdsa.Sign(rand.Reader, key, digest[:])
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign attestation document: %w", err)
+	}
+	signature := make([]byte, 2*len(digest))
+	r.FillBytes(signature[:len(digest)])
+	s.FillBytes(signature[len(digest):])
+	return signature, nil
+}
+
+func errorResponse(code response.ErrorCode) response.Response {
+	return response.Response{Error: code}
+}
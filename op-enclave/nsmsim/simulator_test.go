@@ -0,0 +1,84 @@
+package nsmsim_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/base/op-enclave/op-enclave/nsmsim"
+	"github.com/hf/nsm/request"
+	"github.com/hf/nsm/response"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAttestation(t *testing.T) {
+	ca, err := nsmsim.NewCA()
+	require.NoError(t, err)
+	pcr0 := bytes.Repeat([]byte{0xaa}, 48)
+	sim, err := nsmsim.New(ca, map[uint][]byte{0: pcr0})
+	require.NoError(t, err)
+
+	publicKey := []byte("public key")
+	userData := []byte("user data")
+	nonce := []byte("nonce")
+	attestation, err := sim.Attest(publicKey, userData, nonce)
+	require.NoError(t, err)
+
+	result, err := enclave.VerifyAttestation(attestation, enclave.VerifyOptions{
+		Roots:     ca.Roots(),
+		PCR0:      pcr0,
+		PublicKey: publicKey,
+		Nonce:     nonce,
+		UserData:  userData,
+	})
+	require.NoError(t, err)
+	require.Equal(t, pcr0, result.Document.PCRs[0])
+
+	_, err = enclave.VerifyAttestation(attestation, enclave.VerifyOptions{Roots: ca.Roots(), Nonce: []byte("other")})
+	require.ErrorContains(t, err, "does not match nonce")
+
+	// attestations from the simulator are not trusted by the AWS root, or by another simulator CA
+	_, err = enclave.VerifyAttestation(attestation, enclave.VerifyOptions{})
+	require.Error(t, err)
+	other, err := nsmsim.NewCA()
+	require.NoError(t, err)
+	_, err = enclave.VerifyAttestation(attestation, enclave.VerifyOptions{Roots: other.Roots()})
+	require.Error(t, err)
+}
+
+func TestParseCA(t *testing.T) {
+	ca, err := nsmsim.NewCA()
+	require.NoError(t, err)
+	data, err := ca.MarshalPEM()
+	require.NoError(t, err)
+	parsed, err := nsmsim.ParseCA(data)
+	require.NoError(t, err)
+
+	// attestations signed by the parsed CA verify against the original CA's roots
+	sim, err := nsmsim.New(parsed, nil)
+	require.NoError(t, err)
+	attestation, err := sim.Attest(nil, nil, nil)
+	require.NoError(t, err)
+	_, err = enclave.VerifyAttestation(attestation, enclave.VerifyOptions{Roots: ca.Roots()})
+	require.NoError(t, err)
+}
+
+func TestLockedPCRs(t *testing.T) {
+	ca, err := nsmsim.NewCA()
+	require.NoError(t, err)
+	sim, err := nsmsim.New(ca, map[uint][]byte{0: make([]byte, 48)})
+	require.NoError(t, err)
+	session, err := sim.Open()
+	require.NoError(t, err)
+	defer session.Close()
+
+	res, err := session.Send(&request.ExtendPCR{Index: 0, Data: []byte("data")})
+	require.NoError(t, err)
+	require.Equal(t, response.ECReadOnlyIndex, res.Error)
+
+	res, err = session.Send(&request.ExtendPCR{Index: 16, Data: []byte("data")})
+	require.NoError(t, err)
+	require.Empty(t, res.Error)
+	require.NotNil(t, res.ExtendPCR)
+	require.NotEqual(t, make([]byte, 48), res.ExtendPCR.Data)
+}
@@ -1,6 +1,8 @@
 package flags
 
 import (
+	"time"
+
 	"github.com/ethereum-optimism/optimism/op-proposer/flags"
 	opservice "github.com/ethereum-optimism/optimism/op-service"
 	"github.com/urfave/cli/v2"
@@ -12,10 +14,9 @@ func prefixEnvVar(name string) []string {
 
 var (
 	L2EthRpcFlag = &cli.StringFlag{
-		Name:     "l2-eth-rpc",
-		Usage:    "HTTP provider URL for L2",
-		EnvVars:  prefixEnvVar("L2_ETH_RPC"),
-		Required: true,
+		Name:    "l2-eth-rpc",
+		Usage:   "HTTP provider URL for L2. Required unless --chains is set",
+		EnvVars: prefixEnvVar("L2_ETH_RPC"),
 	}
 	L2RethFlag = &cli.BoolFlag{
 		Name:     "l2-reth",
@@ -24,12 +25,42 @@ var (
 		Value:    false,
 		Required: false,
 	}
-	EnclaveRpcFlag = &cli.StringFlag{
+	EnclaveRpcFlag = &cli.StringSliceFlag{
 		Name:     "enclave-rpc",
-		Usage:    "HTTP provider URL for the enclave service",
+		Usage:    "HTTP provider URLs for the enclave service, comma-separated. Proofs are spread across healthy enclaves",
 		EnvVars:  prefixEnvVar("ENCLAVE_RPC"),
 		Required: true,
 	}
+	EnclaveHealthIntervalFlag = &cli.DurationFlag{
+		Name:    "enclave-health-interval",
+		Usage:   "Interval between enclave health checks",
+		EnvVars: prefixEnvVar("ENCLAVE_HEALTH_INTERVAL"),
+		Value:   10 * time.Second,
+	}
+	PendingStoreFlag = &cli.StringFlag{
+		Name:    "pending-store",
+		Usage:   "Type of store for pending proofs, one of: memory, file, pebble, leveldb",
+		EnvVars: prefixEnvVar("PENDING_STORE"),
+		Value:   "memory",
+	}
+	PendingStorePathFlag = &cli.StringFlag{
+		Name:    "pending-store-path",
+		Usage:   "Path of the file or database directory used to persist pending proofs",
+		EnvVars: prefixEnvVar("PENDING_STORE_PATH"),
+	}
+	ProofConcurrencyFlag = &cli.IntFlag{
+		Name:    "proof-concurrency",
+		Usage:   "Maximum number of blocks (or block ranges) to prove concurrently",
+		EnvVars: prefixEnvVar("PROOF_CONCURRENCY"),
+		Value:   4,
+	}
+	ChainsFlag = &cli.StringFlag{
+		Name: "chains",
+		Usage: "JSON file listing the L2 chains to propose outputs for, instead of the --l2-eth-rpc, --l2-reth, " +
+			"--rollup-rpc and --l2oo-address flags. Each chain is an object with name, l2EthRpc, l2Reth, rollupRpc, " +
+			"l2ooAddress, and optionally privateKey and pendingStorePath. All chains share the enclaves",
+		EnvVars: prefixEnvVar("CHAINS"),
+	}
 	MinProposalIntervalFlag = &cli.Uint64Flag{
 		Name:    "min-proposal-interval",
 		Usage:   "Minimum time between proposals (in L2 blocks)",
@@ -42,7 +73,12 @@ var requiredFlags = []cli.Flag{
 	L2EthRpcFlag,
 	L2RethFlag,
 	EnclaveRpcFlag,
+	EnclaveHealthIntervalFlag,
 	MinProposalIntervalFlag,
+	PendingStoreFlag,
+	PendingStorePathFlag,
+	ProofConcurrencyFlag,
+	ChainsFlag,
 }
 
 func init() {
@@ -0,0 +1,60 @@
+package metrics
+
+import (
+	opmetrics "github.com/ethereum-optimism/optimism/op-service/metrics"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// labeledFactory adds constant labels to every metric it creates, so that metrics with the same
+// name can be registered for several chains.
+type labeledFactory struct {
+	opmetrics.Factory
+	labels prometheus.Labels
+}
+
+var _ opmetrics.Factory = (*labeledFactory)(nil)
+
+func (f *labeledFactory) NewCounter(opts prometheus.CounterOpts) prometheus.Counter {
+	opts.ConstLabels = f.labels
+	return f.Factory.NewCounter(opts)
+}
+
+func (f *labeledFactory) NewCounterVec(opts prometheus.CounterOpts, labelNames []string) *prometheus.CounterVec {
+	opts.ConstLabels = f.labels
+	return f.Factory.NewCounterVec(opts, labelNames)
+}
+
+func (f *labeledFactory) NewGauge(opts prometheus.GaugeOpts) prometheus.Gauge {
+	opts.ConstLabels = f.labels
+	return f.Factory.NewGauge(opts)
+}
+
+func (f *labeledFactory) NewGaugeFunc(opts prometheus.GaugeOpts, function func() float64) prometheus.GaugeFunc {
+	opts.ConstLabels = f.labels
+	return f.Factory.NewGaugeFunc(opts, function)
+}
+
+func (f *labeledFactory) NewGaugeVec(opts prometheus.GaugeOpts, labelNames []string) *prometheus.GaugeVec {
+	opts.ConstLabels = f.labels
+	return f.Factory.NewGaugeVec(opts, labelNames)
+}
+
+func (f *labeledFactory) NewHistogram(opts prometheus.HistogramOpts) prometheus.Histogram {
+	opts.ConstLabels = f.labels
+	return f.Factory.NewHistogram(opts)
+}
+
+func (f *labeledFactory) NewHistogramVec(opts prometheus.HistogramOpts, labelNames []string) *prometheus.HistogramVec {
+	opts.ConstLabels = f.labels
+	return f.Factory.NewHistogramVec(opts, labelNames)
+}
+
+func (f *labeledFactory) NewSummary(opts prometheus.SummaryOpts) prometheus.Summary {
+	opts.ConstLabels = f.labels
+	return f.Factory.NewSummary(opts)
+}
+
+func (f *labeledFactory) NewSummaryVec(opts prometheus.SummaryOpts, labelNames []string) *prometheus.SummaryVec {
+	opts.ConstLabels = f.labels
+	return f.Factory.NewSummaryVec(opts, labelNames)
+}
@@ -1,9 +1,12 @@
 package metrics
 
 import (
+	"context"
 	"io"
+	"time"
 
 	pmetrics "github.com/ethereum-optimism/optimism/op-proposer/metrics"
+	"github.com/ethereum-optimism/optimism/op-service/clock"
 	"github.com/ethereum-optimism/optimism/op-service/eth"
 	opmetrics "github.com/ethereum-optimism/optimism/op-service/metrics"
 	txmetrics "github.com/ethereum-optimism/optimism/op-service/txmgr/metrics"
@@ -16,10 +19,14 @@ import (
 // implements the Registry getter, for metrics HTTP server to hook into
 var _ opmetrics.RegistryMetricer = (*Metrics)(nil)
 
+// Metrics are either the process metrics returned by NewMetrics, or the metrics of a chain
+// returned by ForChain, which also record the chain metrics (refs, txs, L2 caches and proposals).
 type Metrics struct {
 	ns       string
 	registry *prometheus.Registry
 	factory  opmetrics.Factory
+	// chain is the label value of chain metrics, or empty if they are unlabeled
+	chain string
 
 	opmetrics.RefMetrics
 	txmetrics.TxMetrics
@@ -47,13 +54,11 @@ func NewMetrics(procName string) *Metrics {
 	registry := opmetrics.NewRegistry()
 	factory := opmetrics.With(registry)
 
-	return &Metrics{
+	m := &Metrics{
 		ns:       ns,
 		registry: registry,
 		factory:  factory,
 
-		RefMetrics: opmetrics.MakeRefMetrics(ns, factory),
-		TxMetrics:  txmetrics.MakeTxMetrics(ns, factory),
 		RPCMetrics: opmetrics.MakeRPCMetrics(ns, factory),
 
 		info: *factory.NewGaugeVec(prometheus.GaugeOpts{
@@ -69,16 +74,48 @@ func NewMetrics(procName string) *Metrics {
 			Help:      "1 if the op-proposer has finished starting up",
 		}),
 
-		L1Cache:      opmetrics.NewCacheMetrics(factory, ns, "l1_cache", "L1 cache"),
-		L2Cache:      opmetrics.NewCacheMetrics(factory, ns, "l2_cache", "L2 cache"),
-		WitnessCache: opmetrics.NewCacheMetrics(factory, ns, "witness_cache", "Witness cache"),
+		L1Cache: opmetrics.NewCacheMetrics(factory, ns, "l1_cache", "L1 cache"),
+	}
+	return m
+}
 
-		L2OutputProposals: factory.NewCounter(prometheus.CounterOpts{
-			Namespace: ns,
-			Name:      "l2_output_proposals",
-			Help:      "Number of L2 output proposals",
-		}),
+// ForChain returns the metrics of a chain that the process proposes outputs for, which share
+// the process metrics. If chain is empty, the chain metrics are registered without a label,
+// which can only be done once; otherwise they are registered with a chain label, for each
+// of several chains.
+func (m *Metrics) ForChain(chain string) *Metrics {
+	factory := m.factory
+	if chain != "" {
+		factory = &labeledFactory{
+			Factory: m.factory,
+			labels:  prometheus.Labels{"chain": chain},
+		}
+	}
+	c := &Metrics{
+		ns:       m.ns,
+		registry: m.registry,
+		factory:  factory,
+		chain:    chain,
+
+		RPCMetrics: m.RPCMetrics,
+		info:       m.info,
+		up:         m.up,
+		L1Cache:    m.L1Cache,
 	}
+	c.makeChainMetrics()
+	return c
+}
+
+func (m *Metrics) makeChainMetrics() {
+	m.RefMetrics = opmetrics.MakeRefMetrics(m.ns, m.factory)
+	m.TxMetrics = txmetrics.MakeTxMetrics(m.ns, m.factory)
+	m.L2Cache = opmetrics.NewCacheMetrics(m.factory, m.ns, "l2_cache", "L2 cache")
+	m.WitnessCache = opmetrics.NewCacheMetrics(m.factory, m.ns, "witness_cache", "Witness cache")
+	m.L2OutputProposals = m.factory.NewCounter(prometheus.CounterOpts{
+		Namespace: m.ns,
+		Name:      "l2_output_proposals",
+		Help:      "Number of L2 output proposals",
+	})
 }
 
 func (m *Metrics) Registry() *prometheus.Registry {
@@ -86,7 +123,29 @@ func (m *Metrics) Registry() *prometheus.Registry {
 }
 
 func (m *Metrics) StartBalanceMetrics(l log.Logger, client *ethclient.Client, account common.Address) io.Closer {
-	return opmetrics.LaunchBalanceMetrics(l, m.registry, m.ns, client, account)
+	if m.chain == "" {
+		return opmetrics.LaunchBalanceMetrics(l, m.registry, m.ns, client, account)
+	}
+	// the balance metric of op-service includes the account in its help text, which
+	// differs between chains, so chain balances are recorded with an account label instead
+	balanceGauge := m.factory.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: m.ns,
+		Name:      "balance",
+		Help:      "balance (in ether) of the proposer account",
+	}, []string{"account"}).WithLabelValues(account.String())
+	return clock.NewLoopFn(clock.SystemClock, func(ctx context.Context) {
+		ctx, cancel := context.WithTimeout(ctx, 1*time.Minute)
+		defer cancel()
+		bigBal, err := client.BalanceAt(ctx, account, nil)
+		if err != nil {
+			l.Warn("failed to get balance of account", "err", err, "address", account)
+			return
+		}
+		balanceGauge.Set(eth.WeiToEther(bigBal))
+	}, func() error {
+		l.Info("balance metrics shutting down")
+		return nil
+	}, 10*time.Second)
 }
 
 // RecordInfo sets a pseudo-metric that contains versioning and
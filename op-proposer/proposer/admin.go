@@ -0,0 +1,68 @@
+package proposer
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/log"
+	gethrpc "github.com/ethereum/go-ethereum/rpc"
+)
+
+// EnclaveAdminAPI exposes enclave management methods in the admin RPC namespace.
+type EnclaveAdminAPI struct {
+	log  log.Logger
+	pool *EnclavePool
+}
+
+func NewEnclaveAdminAPI(pool *EnclavePool, log log.Logger) *EnclaveAdminAPI {
+	return &EnclaveAdminAPI{
+		log:  log,
+		pool: pool,
+	}
+}
+
+func GetEnclaveAdminAPI(api *EnclaveAdminAPI) gethrpc.API {
+	return gethrpc.API{
+		Namespace: "admin",
+		Service:   api,
+	}
+}
+
+// TransferSignerKey moves the signer key from the enclave at fromUrl to the enclave at toUrl.
+// The enclaves don't need to be part of the proposer's enclave pool.
+func (a *EnclaveAdminAPI) TransferSignerKey(ctx context.Context, fromUrl string, toUrl string) (common.Address, error) {
+	from, closeFrom, err := a.client(ctx, fromUrl)
+	if err != nil {
+		return common.Address{}, err
+	}
+	defer closeFrom()
+	to, closeTo, err := a.client(ctx, toUrl)
+	if err != nil {
+		return common.Address{}, err
+	}
+	defer closeTo()
+
+	signer, err := enclave.TransferSignerKey(ctx, from, to)
+	if err != nil {
+		a.log.Error("Failed to transfer signer key", "from", fromUrl, "to", toUrl, "err", err)
+		return common.Address{}, err
+	}
+	a.log.Info("Transferred signer key", "from", fromUrl, "to", toUrl, "signer", signer)
+
+	// refresh the signer of each pool backend, so the destination enclave is used immediately
+	a.pool.probe(ctx)
+	return signer, nil
+}
+
+func (a *EnclaveAdminAPI) client(ctx context.Context, url string) (enclave.RPC, func(), error) {
+	if client := a.pool.client(url); client != nil {
+		return client, func() {}, nil
+	}
+	client, err := gethrpc.DialContext(ctx, url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to dial enclave %s: %w", url, err)
+	}
+	return &enclave.Client{Client: client}, client.Close, nil
+}
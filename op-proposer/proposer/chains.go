@@ -0,0 +1,95 @@
+package proposer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+
+	"github.com/ethereum/go-ethereum/common"
+)
+
+// DefaultChainName is the name of the only chain when the proposer is configured with flags
+// instead of a chains file.
+const DefaultChainName = "default"
+
+var chainNameRegexp = regexp.MustCompile(`^[a-zA-Z0-9_-]+$`)
+
+// ChainConfig configures one of the L2 chains that outputs are proposed for.
+type ChainConfig struct {
+	// Name identifies the chain in logs and metrics.
+	Name      string `json:"name"`
+	L2EthRpc  string `json:"l2EthRpc"`
+	L2Reth    bool   `json:"l2Reth"`
+	RollupRpc string `json:"rollupRpc"`
+
+	L2OOAddress common.Address `json:"l2ooAddress"`
+
+	// PrivateKey is the key of the account that proposes outputs for the chain. If empty, the
+	// tx manager flags are used; at most one chain can use them, since each chain needs its own
+	// account to manage nonces independently.
+	PrivateKey string `json:"privateKey,omitempty"`
+
+	// PendingStorePath is the path of the chain's pending proposal store. It defaults to the
+	// chain name inside the pending store path flag, which is a directory when there is a chains file.
+	PendingStorePath string `json:"pendingStorePath,omitempty"`
+}
+
+func (c *ChainConfig) Check() error {
+	if !chainNameRegexp.MatchString(c.Name) {
+		return fmt.Errorf("invalid chain name %q, must only contain letters, digits, '_' and '-'", c.Name)
+	}
+	if c.L2EthRpc == "" {
+		return errors.New("missing L2 RPC")
+	}
+	if c.RollupRpc == "" {
+		return errors.New("missing rollup RPC")
+	}
+	if c.L2OOAddress == (common.Address{}) {
+		return errors.New("missing L2OutputOracle address")
+	}
+	return nil
+}
+
+// LoadChainConfigs reads a JSON array of chain configs from a file.
+func LoadChainConfigs(file string) ([]*ChainConfig, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read chains file: %w", err)
+	}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+	var chains []*ChainConfig
+	if err = dec.Decode(&chains); err != nil {
+		return nil, fmt.Errorf("failed to parse chains file %s: %w", file, err)
+	}
+	if len(chains) == 0 {
+		return nil, fmt.Errorf("no chains in chains file %s", file)
+	}
+
+	names := make(map[string]bool)
+	oracles := make(map[common.Address]string)
+	defaultKey := ""
+	for _, chain := range chains {
+		if err = chain.Check(); err != nil {
+			return nil, fmt.Errorf("invalid chain %q: %w", chain.Name, err)
+		}
+		if names[chain.Name] {
+			return nil, fmt.Errorf("duplicate chain name %q", chain.Name)
+		}
+		names[chain.Name] = true
+		if other, ok := oracles[chain.L2OOAddress]; ok {
+			return nil, fmt.Errorf("chains %q and %q have the same L2OutputOracle address", other, chain.Name)
+		}
+		oracles[chain.L2OOAddress] = chain.Name
+		if chain.PrivateKey == "" {
+			if defaultKey != "" {
+				return nil, fmt.Errorf("chains %q and %q both use the default proposer account", defaultKey, chain.Name)
+			}
+			defaultKey = chain.Name
+		}
+	}
+	return chains, nil
+}
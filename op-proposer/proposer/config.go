@@ -1,25 +1,97 @@
 package proposer
 
 import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"time"
+
 	"github.com/base/op-enclave/op-proposer/flags"
 	"github.com/ethereum-optimism/optimism/op-proposer/proposer"
+	opservice "github.com/ethereum-optimism/optimism/op-service"
 	"github.com/urfave/cli/v2"
 )
 
 type CLIConfig struct {
 	*proposer.CLIConfig
-	L2EthRpc            string
-	L2Reth              bool
-	EnclaveRpc          string
-	MinProposalInterval uint64
+	L2EthRpc              string
+	L2Reth                bool
+	EnclaveRpcs           []string
+	EnclaveHealthInterval time.Duration
+	MinProposalInterval   uint64
+	PendingStore          string
+	PendingStorePath      string
+	ProofConcurrency      int
+	ChainsFile            string
 }
 
 func NewConfig(ctx *cli.Context) *CLIConfig {
 	return &CLIConfig{
-		CLIConfig:           proposer.NewConfig(ctx),
-		L2EthRpc:            ctx.String(flags.L2EthRpcFlag.Name),
-		L2Reth:              ctx.Bool(flags.L2RethFlag.Name),
-		EnclaveRpc:          ctx.String(flags.EnclaveRpcFlag.Name),
-		MinProposalInterval: ctx.Uint64(flags.MinProposalIntervalFlag.Name),
+		CLIConfig:             proposer.NewConfig(ctx),
+		L2EthRpc:              ctx.String(flags.L2EthRpcFlag.Name),
+		L2Reth:                ctx.Bool(flags.L2RethFlag.Name),
+		EnclaveRpcs:           ctx.StringSlice(flags.EnclaveRpcFlag.Name),
+		EnclaveHealthInterval: ctx.Duration(flags.EnclaveHealthIntervalFlag.Name),
+		MinProposalInterval:   ctx.Uint64(flags.MinProposalIntervalFlag.Name),
+		PendingStore:          ctx.String(flags.PendingStoreFlag.Name),
+		PendingStorePath:      ctx.String(flags.PendingStorePathFlag.Name),
+		ProofConcurrency:      ctx.Int(flags.ProofConcurrencyFlag.Name),
+		ChainsFile:            ctx.String(flags.ChainsFlag.Name),
+	}
+}
+
+// Check validates the config. The per-chain flags are replaced by the chains file if it is set.
+func (c *CLIConfig) Check() error {
+	if c.ChainsFile == "" {
+		if c.L2EthRpc == "" {
+			return errors.New("missing L2 RPC")
+		}
+		return c.CLIConfig.Check()
+	}
+	if c.L2EthRpc != "" || c.L2Reth || c.RollupRpc != "" || c.L2OOAddress != "" {
+		return errors.New("the L2, rollup and L2OutputOracle flags can't be used with a chains file")
+	}
+	if c.DGFAddress != "" {
+		return errors.New("the DisputeGameFactory is not supported")
+	}
+	for _, check := range []func() error{
+		c.RPCConfig.Check,
+		c.MetricsConfig.Check,
+		c.PprofConfig.Check,
+		c.TxMgrConfig.Check,
+	} {
+		if err := check(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Chains returns the configs of the chains to propose outputs for, from the chains file if set,
+// or otherwise a single chain from the flags.
+func (c *CLIConfig) Chains() ([]*ChainConfig, error) {
+	if c.ChainsFile == "" {
+		l2ooAddress, err := opservice.ParseAddress(c.L2OOAddress)
+		if err != nil {
+			return nil, fmt.Errorf("invalid L2OutputOracle address: %w", err)
+		}
+		return []*ChainConfig{{
+			Name:             DefaultChainName,
+			L2EthRpc:         c.L2EthRpc,
+			L2Reth:           c.L2Reth,
+			RollupRpc:        c.RollupRpc,
+			L2OOAddress:      l2ooAddress,
+			PendingStorePath: c.PendingStorePath,
+		}}, nil
+	}
+	chains, err := LoadChainConfigs(c.ChainsFile)
+	if err != nil {
+		return nil, err
+	}
+	for _, chain := range chains {
+		if chain.PendingStorePath == "" && c.PendingStorePath != "" {
+			chain.PendingStorePath = filepath.Join(c.PendingStorePath, chain.Name)
+		}
 	}
+	return chains, nil
 }
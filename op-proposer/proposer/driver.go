@@ -16,12 +16,25 @@ import (
 	"github.com/ethereum/go-ethereum"
 	"github.com/ethereum/go-ethereum/accounts/abi"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/core/types"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/ethereum/go-ethereum/rpc"
 )
 
-const aggregateBatchSize = 1000
+const (
+	aggregateBatchSize = 1000
+	// executeRangeBatchSize is the maximum number of blocks proven in a single
+	// enclave call, when the enclave supports range execution
+	executeRangeBatchSize = 100
+	// blockhashWindow is the number of recent L1 blocks whose hash is available onchain, and
+	// blockhashMargin leaves time for the proposal transaction to be included
+	blockhashWindow = 256
+	blockhashMargin = 10
+	// anchorDepth is the number of blocks behind the L1 head that old proposals are anchored to,
+	// so that the anchor is unlikely to be reorged out
+	anchorDepth = 10
+)
 
 var (
 	ErrProposerNotRunning = errors.New("proposer is not running")
@@ -47,6 +60,7 @@ type DriverSetup struct {
 	L2Client      L2Client
 	RollupClient  RollupClient
 	EnclaveClient enclave.RPC
+	Store         ProposalStore
 }
 
 // L2OutputSubmitter is responsible for proposing outputs
@@ -65,8 +79,9 @@ type L2OutputSubmitter struct {
 	ooContract OOContract
 	ooABI      *abi.ABI
 
-	prover  *Prover
-	pending []*Proposal
+	prover      *Prover
+	pending     []*Proposal
+	concurrency int
 }
 
 // NewL2OutputSubmitter creates a new L2 Output Submitter
@@ -106,7 +121,13 @@ func newL2OOSubmitter(ctx context.Context, cancel context.CancelFunc, setup Driv
 		return nil, err
 	}
 
-	prover, err := NewProver(cCtx, setup.L1Client, setup.L2Client, setup.RollupClient, setup.EnclaveClient)
+	configHash, err := ooContract.ConfigHash(&bind.CallOpts{Context: cCtx})
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to fetch config hash: %w", err)
+	}
+
+	prover, err := NewProver(cCtx, setup.L1Client, setup.L2Client, setup.RollupClient, setup.EnclaveClient, configHash)
 	if err != nil {
 		cancel()
 		return nil, err
@@ -121,6 +142,8 @@ func newL2OOSubmitter(ctx context.Context, cancel context.CancelFunc, setup Driv
 		ooContract: ooContract,
 		ooABI:      parsed,
 		prover:     prover,
+
+		concurrency: max(setup.Cfg.ProofConcurrency, 1),
 	}, nil
 }
 
@@ -194,6 +217,7 @@ func (l *L2OutputSubmitter) loop() {
 	defer l.wg.Done()
 	defer l.Log.Info("loop returning")
 	ctx := l.ctx
+	l.restorePending(ctx)
 	ticker := time.NewTicker(l.Cfg.PollInterval)
 	defer ticker.Stop()
 	for {
@@ -242,44 +266,244 @@ func (l *L2OutputSubmitter) generateOutputs(ctx context.Context, latestOutput bi
 	latestOutputNumber := latestOutput.L2BlockNumber.Uint64()
 
 	// clear out already submitted outputs
-	for len(l.pending) > 0 && l.pending[0].From.Number-1 < latestOutputNumber {
-		l.pending = l.pending[1:]
+	pending := l.pending
+	for len(pending) > 0 && pending[0].From.Number-1 < latestOutputNumber {
+		pending = pending[1:]
 	}
 
+	if len(pending) > 0 && pending[0].From.Number-1 != latestOutputNumber {
+		l.Log.Warn("Pending outputs are not contiguous with the latest output",
+			"latest", latestOutputNumber,
+			"pending", pending[0].From.Number-1)
+		pending = nil
+	}
+	if len(pending) != len(l.pending) {
+		l.setPending(pending)
+	}
 	if len(l.pending) > 0 {
-		if l.pending[0].From.Number-1 != latestOutputNumber {
-			l.Log.Warn("Pending outputs are not contiguous with the latest output",
-				"latest", latestOutputNumber,
-				"pending", l.pending[0].From.Number-1)
-			l.pending = nil
-		} else {
-			latestOutputNumber = l.pending[len(l.pending)-1].To.Number
-		}
+		latestOutputNumber = l.pending[len(l.pending)-1].To.Number
+	}
+
+	supportsRange, err := l.prover.SupportsRange(ctx)
+	if err != nil {
+		return err
+	}
+	rangeSize := uint64(1)
+	if supportsRange {
+		rangeSize = executeRangeBatchSize
+	}
+
+	head, err := l.L2Client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to get latest L2 block: %w", err)
+	}
+	start := latestOutputNumber + 1
+	end := min(latestOutputNumber+aggregateBatchSize, head.Number.Uint64())
+	if start > end {
+		return nil
 	}
 
 	// calculate `aggregateBatchSize` proofs at once, which are then aggregated in `nextOutput`
-	for i := uint64(0); i < aggregateBatchSize; i++ {
-		number := i + latestOutputNumber + 1
+	err = l.generateProposals(ctx, start, end, rangeSize)
+	if err != nil {
+		// back off when the enclave or L2 RPC is struggling to keep up
+		l.concurrency = max(l.concurrency/2, 1)
+	} else {
+		l.concurrency = min(l.concurrency+1, max(l.Cfg.ProofConcurrency, 1))
+	}
+	return err
+}
+
+// generateProposals proves the blocks from start to end (inclusive) in chunks of rangeSize blocks,
+// using up to l.concurrency concurrent workers. Proposals are appended to the pending queue in
+// block order as they complete; proving stops at the first chunk that fails.
+func (l *L2OutputSubmitter) generateProposals(ctx context.Context, start uint64, end uint64, rangeSize uint64) error {
+	type chunk struct {
+		start uint64
+		count uint64
+	}
+	var chunks []chunk
+	for number := start; number <= end; number += rangeSize {
+		chunks = append(chunks, chunk{number, min(rangeSize, end-number+1)})
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	var wg sync.WaitGroup
+	defer func() {
+		// stop dispatching, and wait for in-flight proofs to be cancelled
+		cancel()
+		wg.Wait()
+	}()
+
+	results := make([]chan result[*Proposal], len(chunks))
+	for i := range results {
+		results[i] = make(chan result[*Proposal], 1)
+	}
+	workers := make(chan struct{}, l.concurrency)
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i, c := range chunks {
+			select {
+			case workers <- struct{}{}:
+			case <-ctx.Done():
+			}
+			// select picks randomly if a worker is free as well, so check for cancellation
+			// separately to never start new proofs once cancelled
+			if err := ctx.Err(); err != nil {
+				results[i] <- result[*Proposal]{err: err}
+				continue
+			}
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer func() { <-workers }()
+				proposal, err := l.generateProposal(ctx, c.start, c.count)
+				results[i] <- result[*Proposal]{proposal, err}
+			}()
+		}
+	}()
+
+	for i, c := range chunks {
+		r := <-results[i]
+		if r.err != nil {
+			return fmt.Errorf("failed to generate proof for blocks %d-%d: %w", c.start, c.start+c.count-1, r.err)
+		}
+		proposal := r.value
+		l.Log.Info("Generated proof for blocks",
+			"from", l2BlockRefToBlockID(proposal.From), "to", l2BlockRefToBlockID(proposal.To), "l1Origin", proposal.To.L1Origin,
+			"withdrawals", proposal.Withdrawals, "output", proposal.Output.OutputRoot.String())
+		l.appendPending(proposal)
+		if proposal.To.Number != c.start+c.count-1 {
+			// the chain head moved backwards while proving; later chunks are not contiguous
+			break
+		}
+	}
+	return nil
+}
+
+func (l *L2OutputSubmitter) generateProposal(ctx context.Context, start uint64, count uint64) (*Proposal, error) {
+	blocks, err := l.fetchBlocks(ctx, start, count)
+	if err != nil {
+		return nil, err
+	}
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("block %d not found", start)
+	}
+	return l.prover.GenerateRange(ctx, blocks)
+}
+
+// fetchBlocks returns up to count consecutive L2 blocks starting at start, stopping early at the chain head.
+func (l *L2OutputSubmitter) fetchBlocks(ctx context.Context, start uint64, count uint64) ([]*types.Block, error) {
+	var blocks []*types.Block
+	for number := start; number < start+count; number++ {
 		block, err := l.L2Client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
 		if errors.Is(err, ethereum.NotFound) {
 			break
 		}
 		if err != nil {
-			return fmt.Errorf("failed to get block %d: %w", number, err)
+			return nil, fmt.Errorf("failed to get block %d: %w", number, err)
 		}
+		blocks = append(blocks, block)
+	}
+	return blocks, nil
+}
+
+// appendPending adds a proposal to the end of the pending queue, and persists it to the store.
+func (l *L2OutputSubmitter) appendPending(proposal *Proposal) {
+	l.pending = append(l.pending, proposal)
+	if err := l.Store.Append(proposal); err != nil {
+		l.Log.Warn("Failed to persist pending proposal", "err", err)
+	}
+}
+
+// setPending replaces the pending queue, and persists it to the store.
+func (l *L2OutputSubmitter) setPending(pending []*Proposal) {
+	l.pending = pending
+	if err := l.Store.Replace(pending); err != nil {
+		l.Log.Warn("Failed to persist pending proposals", "err", err)
+	}
+}
+
+// restorePending loads pending proposals from the store, keeping the proposals
+// that are contiguous with the latest output and still on the canonical L2 chain.
+func (l *L2OutputSubmitter) restorePending(ctx context.Context) {
+	stored, err := l.Store.Load()
+	if err != nil {
+		l.Log.Warn("Failed to load pending proposals", "err", err)
+		return
+	}
+	if len(stored) == 0 {
+		return
+	}
+
+	latestOutput, err := l.ooContract.LatestL2Output(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		l.Log.Warn("Failed to get latest output, discarding stored proposals", "err", err)
+		l.setPending(nil)
+		return
+	}
+	latestOutputNumber := latestOutput.L2BlockNumber.Uint64()
 
-		proposal, err := l.prover.Generate(ctx, block)
+	var pending []*Proposal
+	for _, proposal := range stored {
+		if proposal.To.Number <= latestOutputNumber {
+			// already proposed
+			continue
+		}
+		expected := latestOutputNumber + 1
+		if len(pending) > 0 {
+			expected = pending[len(pending)-1].To.Number + 1
+		}
+		if proposal.From.Number != expected {
+			l.Log.Warn("Stored proposal is not contiguous, discarding remaining proposals",
+				"expected", expected, "from", proposal.From.Number)
+			break
+		}
+		canonical, err := l.isCanonical(ctx, proposal)
 		if err != nil {
-			return fmt.Errorf("failed to generate proof for block %d: %w", number, err)
+			l.Log.Warn("Failed to check stored proposal, discarding remaining proposals", "err", err)
+			break
+		}
+		if !canonical {
+			l.Log.Warn("Stored proposal is not canonical, discarding remaining proposals",
+				"from", l2BlockRefToBlockID(proposal.From), "to", l2BlockRefToBlockID(proposal.To))
+			break
 		}
+		pending = append(pending, proposal)
+	}
 
-		l.Log.Info("Generated proof for block",
-			"block", l2BlockRefToBlockID(proposal.To), "l1Origin", proposal.To.L1Origin,
-			"withdrawals", proposal.Withdrawals, "output", proposal.Output.OutputRoot.String())
-		l.pending = append(l.pending, proposal)
+	l.Log.Info("Restored pending proposals", "stored", len(stored), "restored", len(pending))
+	l.setPending(pending)
+}
+
+// isCanonical checks that the first and last blocks of the proposal are on the canonical L2 chain.
+func (l *L2OutputSubmitter) isCanonical(ctx context.Context, proposal *Proposal) (bool, error) {
+	for _, ref := range []eth.L2BlockRef{proposal.From, proposal.To} {
+		header, err := l.L2Client.HeaderByNumber(ctx, new(big.Int).SetUint64(ref.Number))
+		if errors.Is(err, ethereum.NotFound) {
+			return false, nil
+		}
+		if err != nil {
+			return false, fmt.Errorf("failed to get block %d: %w", ref.Number, err)
+		}
+		if header.Hash() != ref.Hash {
+			return false, nil
+		}
 	}
+	return true, nil
+}
 
-	return nil
+// isCanonicalL1 checks that the L1 block is on the canonical L1 chain.
+func (l *L2OutputSubmitter) isCanonicalL1(ctx context.Context, block eth.BlockID) (bool, error) {
+	header, err := l.L1Client.HeaderByNumber(ctx, new(big.Int).SetUint64(block.Number))
+	if errors.Is(err, ethereum.NotFound) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get L1 block %d: %w", block.Number, err)
+	}
+	return header.Hash() == block.Hash, nil
 }
 
 func (l *L2OutputSubmitter) nextOutput(ctx context.Context, latestOutput bindings.TypesOutputProposal) (*Proposal, bool, error) {
@@ -303,14 +527,16 @@ func (l *L2OutputSubmitter) nextOutput(ctx context.Context, latestOutput binding
 		aggregated, err := l.prover.Aggregate(ctx, latestOutput.OutputRoot, batch)
 		if err != nil {
 			var rpcError rpc.Error
-			if errors.As(err, &rpcError) {
-				// if we received an explicit error from the enclave (like "invalid signer"), clear the pending proofs
+			if errors.As(err, &rpcError) || errors.Is(err, ErrUnknownSigner) {
+				// the enclave rejected the proofs (like "invalid signature"), or every enclave reported
+				// a different signer, so the proofs can never be aggregated; clear the pending proofs.
+				// Other errors, like no enclave being reachable, are retried with the same proofs.
 				l.Log.Warn("Non-recoverable error aggregating proofs", "err", err)
-				l.pending = nil
+				l.setPending(nil)
 			}
 			return nil, false, err
 		}
-		l.pending = append([]*Proposal{aggregated}, l.pending[batchLength:]...)
+		l.setPending(append([]*Proposal{aggregated}, l.pending[batchLength:]...))
 		count -= batchLength - 1
 		l.Log.Info("Aggregated proofs",
 			"output", aggregated.Output.OutputRoot.String(), "blocks", batchLength, "remaining", count-1,
@@ -318,38 +544,79 @@ func (l *L2OutputSubmitter) nextOutput(ctx context.Context, latestOutput binding
 	}
 	proposal := l.pending[0]
 
-	if proposal.To.Number < latestSafe.Number {
-		l.Log.Info("Aggregated output is not the latest safe block, waiting for more proofs",
-			"aggregated", l2BlockRefToBlockID(proposal.To), "latestSafe", latestSafe)
-		return nil, false, nil
+	// range proofs end on batch boundaries, so the aggregated output can end before the latest
+	// safe block; it is proposed as is, and the remaining blocks are covered by the next output
+	canonical, err := l.isCanonical(ctx, proposal)
+	if err != nil {
+		return nil, false, err
 	}
-
-	if proposal.To.Hash != latestSafe.Hash {
-		l.Log.Warn("Aggregated output does not match the latest batched block, possible reorg",
+	if !canonical {
+		l.Log.Warn("Aggregated output is not on the canonical chain, possible reorg",
 			"aggregated", l2BlockRefToBlockID(proposal.To), "latestSafe", latestSafe)
-		l.pending = nil
+		l.setPending(nil)
 		return nil, false, nil
 	}
+	if proposal.L1Anchor != nil {
+		canonical, err := l.isCanonicalL1(ctx, *proposal.L1Anchor)
+		if err != nil {
+			return nil, false, err
+		}
+		if !canonical {
+			// the output is only signed against the anchor, so it has to be generated again
+			l.Log.Warn("L1 anchor of output is not on the canonical chain, possible reorg",
+				"aggregated", l2BlockRefToBlockID(proposal.To), "l1Anchor", proposal.L1Anchor)
+			l.setPending(nil)
+			return nil, false, nil
+		}
+	}
 
 	shouldPropose := proposal.Withdrawals ||
 		(l.Cfg.MinProposalInterval > 0 &&
-			latestSafe.Number-latestOutput.L2BlockNumber.Uint64() > l.Cfg.MinProposalInterval)
+			proposal.To.Number-latestOutput.L2BlockNumber.Uint64() > l.Cfg.MinProposalInterval)
 
 	if shouldPropose {
 		latestL1Number, err := l.L1Client.BlockNumber(l.ctx)
 		if err != nil {
 			log.Warn("Failed to get latest block header", "err", err)
 			shouldPropose = false
-		} else if proposal.To.L1Origin.Number <= latestL1Number-(256-10) {
-			// only submit onchain if within the blockhash window - 10
-			log.Warn("Not submitting proposal, block is too old", "l1Origin", proposal.To.L1Origin.Number, "l1Latest", latestL1Number)
-			shouldPropose = false
+		} else if proposal.L1Block().Number+blockhashWindow-blockhashMargin <= latestL1Number {
+			// only submit onchain if within the blockhash window - 10, otherwise anchor the
+			// proposal to a recent descendant of the L1 block it is signed against
+			anchored, err := l.anchorProposal(ctx, latestOutput.OutputRoot, proposal, latestL1Number)
+			if err != nil {
+				log.Warn("Not submitting proposal, block is too old", "l1Block", proposal.L1Block().Number, "l1Latest", latestL1Number, "err", err)
+				shouldPropose = false
+			} else {
+				// keep the anchored proposal, so that it is only anchored again once the
+				// anchor itself gets too old, from the anchor rather than the L1 origin
+				l.setPending(append([]*Proposal{anchored}, l.pending[1:]...))
+				proposal = anchored
+			}
 		}
 	}
 
 	return proposal, shouldPropose, nil
 }
 
+// anchorProposal re-signs a proposal whose L1 block is outside the blockhash window against a
+// recent L1 block.
+func (l *L2OutputSubmitter) anchorProposal(ctx context.Context, prevOutputRoot common.Hash, proposal *Proposal, latestL1Number uint64) (*Proposal, error) {
+	supportsAnchor, err := l.prover.SupportsAnchor(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if !supportsAnchor {
+		return nil, errors.New("enclave does not support anchoring proposals")
+	}
+	anchored, err := l.prover.Anchor(ctx, prevOutputRoot, proposal, latestL1Number-anchorDepth)
+	if err != nil {
+		return nil, err
+	}
+	l.Log.Info("Anchored proposal to a recent L1 block",
+		"block", l2BlockRefToBlockID(proposal.To), "l1Origin", proposal.To.L1Origin, "l1Anchor", anchored.L1Anchor)
+	return anchored, nil
+}
+
 func (l *L2OutputSubmitter) proposeOutput(ctx context.Context, proposal *Proposal) {
 	cCtx, cancel := context.WithTimeout(ctx, 10*time.Minute)
 	defer cancel()
@@ -401,7 +668,7 @@ func proposeL2OutputTxData(abi *abi.ABI, proposal *Proposal) ([]byte, error) {
 		"proposeL2Output",
 		proposal.Output.OutputRoot,
 		new(big.Int).SetUint64(proposal.To.Number),
-		new(big.Int).SetUint64(proposal.To.L1Origin.Number),
+		new(big.Int).SetUint64(proposal.L1Block().Number),
 		sig,
 	)
 }
@@ -0,0 +1,101 @@
+package proposer
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"path/filepath"
+	"testing"
+
+	"github.com/base/op-enclave/bindings"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+type testOutputOracle struct {
+	OOContract
+	latest bindings.TypesOutputProposal
+}
+
+func (o *testOutputOracle) LatestL2Output(opts *bind.CallOpts) (bindings.TypesOutputProposal, error) {
+	return o.latest, nil
+}
+
+type testL2Client struct {
+	L2Client
+	headers []*types.Header
+}
+
+func (c *testL2Client) HeaderByNumber(ctx context.Context, number *big.Int) (*types.Header, error) {
+	return c.headers[number.Uint64()], nil
+}
+
+func (c *testL2Client) ref(number uint64) eth.L2BlockRef {
+	return eth.L2BlockRef{Number: number, Hash: c.headers[number].Hash()}
+}
+
+type testRollupClient struct {
+	RollupClient
+	status *eth.SyncStatus
+}
+
+func (c *testRollupClient) SyncStatus(ctx context.Context) (*eth.SyncStatus, error) {
+	return c.status, nil
+}
+
+func TestRestorePendingWithUnavailableEnclave(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	l2 := &testL2Client{}
+	for number := range 3 {
+		l2.headers = append(l2.headers, &types.Header{Number: big.NewInt(int64(number))})
+	}
+	latestOutput := bindings.TypesOutputProposal{L2BlockNumber: big.NewInt(0)}
+
+	store, err := OpenProposalStore(StoreTypeFile, filepath.Join(t.TempDir(), "pending"))
+	require.NoError(t, err)
+	defer store.Close()
+	var stored []*Proposal
+	for i, output := range signedProposals(t, key, common.Hash{}, latestOutput.OutputRoot, 2) {
+		proposal := &Proposal{Output: output, From: l2.ref(uint64(i + 1)), To: l2.ref(uint64(i + 1))}
+		require.NoError(t, store.Append(proposal))
+		stored = append(stored, proposal)
+	}
+
+	backend := &testEnclave{err: errors.New("connection refused")}
+	l := &L2OutputSubmitter{
+		DriverSetup: DriverSetup{
+			Log:          log.New(),
+			L2Client:     l2,
+			RollupClient: &testRollupClient{status: &eth.SyncStatus{FinalizedL2: l2.ref(2)}},
+			Store:        store,
+		},
+		ooContract: &testOutputOracle{latest: latestOutput},
+		prover:     &Prover{enclave: newTestPool(t, backend)},
+	}
+	l.restorePending(ctx)
+	requireProposals(t, stored, l.pending)
+
+	// the enclave is unreachable, so the proposals can't be aggregated, but are kept
+	_, _, err = l.nextOutput(ctx, latestOutput)
+	require.ErrorIs(t, err, ErrNoHealthyEnclave)
+	requireProposals(t, stored, l.pending)
+	proposals, err := store.Load()
+	require.NoError(t, err)
+	requireProposals(t, stored, proposals)
+
+	// once the enclave is reachable, the restored proposals are aggregated
+	backend.err = nil
+	backend.signer = crypto.PubkeyToAddress(key.PublicKey)
+	l.prover.enclave.(*EnclavePool).probe(ctx)
+	proposal, _, err := l.nextOutput(ctx, latestOutput)
+	require.NoError(t, err)
+	require.Equal(t, stored[0].From, proposal.From)
+	require.Equal(t, stored[1].To, proposal.To)
+}
@@ -0,0 +1,399 @@
+package proposer
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/core/stateless"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+var (
+	ErrNoHealthyEnclave = errors.New("no healthy enclave available")
+	// ErrUnknownSigner is returned when proposals were signed by a key that no configured enclave holds.
+	ErrUnknownSigner = errors.New("no enclave holds the signer key of the proposals")
+)
+
+// methodNotFoundCode is the JSON-RPC error code returned for unknown methods.
+const methodNotFoundCode = -32601
+
+type enclaveBackend struct {
+	url    string
+	client enclave.RPC
+
+	mutex   sync.RWMutex
+	healthy bool
+	signer  common.Address
+}
+
+func (b *enclaveBackend) status() (bool, common.Address) {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+	return b.healthy, b.signer
+}
+
+func (b *enclaveBackend) markUnhealthy() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	b.healthy = false
+}
+
+// EnclavePool spreads enclave calls across multiple enclave backends. Backends are probed
+// periodically with Status; unreachable backends are skipped until they recover.
+//
+// Proofs are only generated by healthy backends that share the signer key of the first
+// healthy backend (in configuration order), so that they can always be aggregated together.
+// Aggregation is routed to a backend holding the key that signed the proofs.
+type EnclavePool struct {
+	log            log.Logger
+	backends       []*enclaveBackend
+	probeTimeout   time.Duration
+	healthInterval time.Duration
+	next           atomic.Uint64
+
+	done chan struct{}
+	wg   sync.WaitGroup
+}
+
+var _ enclave.RPC = (*EnclavePool)(nil)
+
+func NewEnclavePool(log log.Logger, urls []string, clients []enclave.RPC, healthInterval time.Duration, probeTimeout time.Duration) *EnclavePool {
+	backends := make([]*enclaveBackend, len(clients))
+	for i, client := range clients {
+		backends[i] = &enclaveBackend{
+			url:    urls[i],
+			client: client,
+		}
+	}
+	return &EnclavePool{
+		log:            log,
+		backends:       backends,
+		probeTimeout:   probeTimeout,
+		healthInterval: healthInterval,
+		done:           make(chan struct{}),
+	}
+}
+
+// Start probes all backends, and then keeps probing them in the background until Close is called.
+func (p *EnclavePool) Start(ctx context.Context) {
+	p.probe(ctx)
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ticker := time.NewTicker(p.healthInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				p.probe(context.Background())
+			case <-p.done:
+				return
+			}
+		}
+	}()
+}
+
+func (p *EnclavePool) Close() {
+	close(p.done)
+	p.wg.Wait()
+}
+
+func (p *EnclavePool) probe(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, b := range p.backends {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			p.probeBackend(ctx, b)
+		}()
+	}
+	wg.Wait()
+}
+
+func (p *EnclavePool) probeBackend(ctx context.Context, b *enclaveBackend) {
+	ctx, cancel := context.WithTimeout(ctx, p.probeTimeout)
+	defer cancel()
+
+	signer, status, err := probeSigner(ctx, b.client)
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+	if err != nil {
+		if b.healthy {
+			p.log.Warn("Enclave is unhealthy", "url", b.url, "err", err)
+		}
+		b.healthy = false
+		return
+	}
+	if !b.healthy || b.signer != signer {
+		if status != nil {
+			p.log.Info("Enclave is healthy", "url", b.url, "signer", signer, "version", status.Version,
+				"pcr0", crypto.Keccak256Hash(status.PCR0), "uptime", time.Duration(status.Uptime)*time.Second)
+		} else {
+			p.log.Info("Enclave is healthy", "url", b.url, "signer", signer)
+		}
+	}
+	b.healthy = true
+	b.signer = signer
+}
+
+// probeSigner returns the signer address and status of the enclave. Enclaves that predate
+// enclave_status are probed with SignerPublicKey instead, and return a nil status.
+func probeSigner(ctx context.Context, client enclave.RPC) (common.Address, *enclave.Status, error) {
+	status, err := client.Status(ctx)
+	if err == nil {
+		return status.SignerAddress, status, nil
+	}
+	var rpcErr rpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.ErrorCode() != methodNotFoundCode {
+		return common.Address{}, nil, err
+	}
+	publicKey, err := client.SignerPublicKey(ctx)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	pub, err := crypto.UnmarshalPubkey(publicKey)
+	if err != nil {
+		return common.Address{}, nil, err
+	}
+	return crypto.PubkeyToAddress(*pub), nil, nil
+}
+
+// client returns the client of the backend with the given URL, or nil if there is no such backend.
+func (p *EnclavePool) client(url string) enclave.RPC {
+	for _, b := range p.backends {
+		if b.url == url {
+			return b.client
+		}
+	}
+	return nil
+}
+
+// activeBackends returns the healthy backends that share the signer key of the first healthy backend.
+func (p *EnclavePool) activeBackends() []*enclaveBackend {
+	var active []*enclaveBackend
+	var activeSigner common.Address
+	for _, b := range p.backends {
+		healthy, signer := b.status()
+		if !healthy {
+			continue
+		}
+		if len(active) == 0 {
+			activeSigner = signer
+		}
+		if signer == activeSigner {
+			active = append(active, b)
+		}
+	}
+	return active
+}
+
+// call invokes f on the active backends in round-robin order, failing over to the next
+// backend if the backend could not be reached.
+func call[E any](ctx context.Context, p *EnclavePool, f func(client enclave.RPC) (E, error)) (E, error) {
+	var empty E
+	active := p.activeBackends()
+	if len(active) == 0 {
+		return empty, ErrNoHealthyEnclave
+	}
+	offset := p.next.Add(1)
+	var err error
+	for i := range active {
+		b := active[(offset+uint64(i))%uint64(len(active))]
+		var value E
+		value, err = f(b.client)
+		if err == nil || !isConnectionError(ctx, err) {
+			return value, err
+		}
+		p.log.Warn("Enclave request failed, trying next enclave", "url", b.url, "err", err)
+		b.markUnhealthy()
+	}
+	return empty, err
+}
+
+// isConnectionError returns true if the error was caused by failing to reach the enclave,
+// rather than by the enclave rejecting the request.
+func isConnectionError(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var rpcErr rpc.Error
+	return !errors.As(err, &rpcErr)
+}
+
+func (p *EnclavePool) SignerPublicKey(ctx context.Context) (hexutil.Bytes, error) {
+	return call(ctx, p, func(client enclave.RPC) (hexutil.Bytes, error) {
+		return client.SignerPublicKey(ctx)
+	})
+}
+
+func (p *EnclavePool) SignerAttestation(ctx context.Context, nonce *hexutil.Bytes, userData *hexutil.Bytes) (hexutil.Bytes, error) {
+	return call(ctx, p, func(client enclave.RPC) (hexutil.Bytes, error) {
+		return client.SignerAttestation(ctx, nonce, userData)
+	})
+}
+
+func (p *EnclavePool) DecryptionPublicKey(ctx context.Context) (hexutil.Bytes, error) {
+	return call(ctx, p, func(client enclave.RPC) (hexutil.Bytes, error) {
+		return client.DecryptionPublicKey(ctx)
+	})
+}
+
+func (p *EnclavePool) DecryptionAttestation(ctx context.Context, nonce *hexutil.Bytes, userData *hexutil.Bytes) (hexutil.Bytes, error) {
+	return call(ctx, p, func(client enclave.RPC) (hexutil.Bytes, error) {
+		return client.DecryptionAttestation(ctx, nonce, userData)
+	})
+}
+
+func (p *EnclavePool) EncryptedSignerKey(ctx context.Context, attestation hexutil.Bytes) (hexutil.Bytes, error) {
+	return call(ctx, p, func(client enclave.RPC) (hexutil.Bytes, error) {
+		return client.EncryptedSignerKey(ctx, attestation)
+	})
+}
+
+func (p *EnclavePool) SetSignerKey(ctx context.Context, encrypted hexutil.Bytes, attestation hexutil.Bytes) error {
+	return errors.New("setting the signer key must target a specific enclave")
+}
+
+// SetTrustedPCR0s passes the trusted PCR0s to all backends.
+func (p *EnclavePool) SetTrustedPCR0s(ctx context.Context, trusted *enclave.TrustedPCR0s) error {
+	var result error
+	for _, b := range p.backends {
+		if err := b.client.SetTrustedPCR0s(ctx, trusted); err != nil {
+			result = errors.Join(result, fmt.Errorf("failed to set trusted PCR0s on %s: %w", b.url, err))
+		}
+	}
+	return result
+}
+
+// Capabilities returns the capabilities supported by all active backends.
+func (p *EnclavePool) Capabilities(ctx context.Context) ([]string, error) {
+	active := p.activeBackends()
+	if len(active) == 0 {
+		return nil, ErrNoHealthyEnclave
+	}
+	var capabilities []string
+	for i, b := range active {
+		c, err := b.client.Capabilities(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch capabilities from %s: %w", b.url, err)
+		}
+		if i == 0 {
+			capabilities = c
+		} else {
+			capabilities = slices.DeleteFunc(capabilities, func(s string) bool {
+				return !slices.Contains(c, s)
+			})
+		}
+	}
+	return capabilities, nil
+}
+
+// Status returns the status of one of the active backends.
+func (p *EnclavePool) Status(ctx context.Context) (*enclave.Status, error) {
+	return call(ctx, p, func(client enclave.RPC) (*enclave.Status, error) {
+		return client.Status(ctx)
+	})
+}
+
+func (p *EnclavePool) ExecuteStateless(ctx context.Context, config *enclave.PerChainConfig, l1Origin *types.Header, l1Receipts types.Receipts, previousBlockTxs []hexutil.Bytes, blockHeader *types.Header, sequencedTxs []hexutil.Bytes, witness *stateless.ExecutionWitness, messageAccount *eth.AccountResult, prevMessageAccountHash common.Hash) (*enclave.Proposal, error) {
+	return call(ctx, p, func(client enclave.RPC) (*enclave.Proposal, error) {
+		return client.ExecuteStateless(ctx, config, l1Origin, l1Receipts, previousBlockTxs, blockHeader, sequencedTxs, witness, messageAccount, prevMessageAccountHash)
+	})
+}
+
+func (p *EnclavePool) ExecuteStatelessRange(ctx context.Context, config *enclave.PerChainConfig, blocks []*enclave.StatelessBlock) (*enclave.Proposal, error) {
+	return call(ctx, p, func(client enclave.RPC) (*enclave.Proposal, error) {
+		return client.ExecuteStatelessRange(ctx, config, blocks)
+	})
+}
+
+// Aggregate routes the aggregation to a healthy backend holding the key that signed the proposals.
+func (p *EnclavePool) Aggregate(ctx context.Context, configHash common.Hash, prevOutputRoot common.Hash, proposals []*enclave.Proposal) (*enclave.Proposal, error) {
+	signer, err := proposalsSigner(configHash, prevOutputRoot, proposals)
+	if err != nil {
+		return nil, err
+	}
+	return callSigner(ctx, p, signer, func(client enclave.RPC) (*enclave.Proposal, error) {
+		return client.Aggregate(ctx, configHash, prevOutputRoot, proposals)
+	})
+}
+
+// AnchorProposal routes the anchoring to a healthy backend holding the key that signed the proposal.
+func (p *EnclavePool) AnchorProposal(ctx context.Context, configHash common.Hash, prevOutputRoot common.Hash, proposal *enclave.Proposal, l1Headers []*types.Header) (*enclave.Proposal, error) {
+	signer, err := proposalsSigner(configHash, prevOutputRoot, []*enclave.Proposal{proposal})
+	if err != nil {
+		return nil, err
+	}
+	return callSigner(ctx, p, signer, func(client enclave.RPC) (*enclave.Proposal, error) {
+		return client.AnchorProposal(ctx, configHash, prevOutputRoot, proposal, l1Headers)
+	})
+}
+
+// callSigner calls f on the healthy backends holding the signer key in turn, until one of them
+// doesn't fail with a connection error. ErrUnknownSigner is only returned once every backend has
+// reported its signer and none of them holds the key, since a backend that hasn't been reached
+// yet could still hold it.
+func callSigner[E any](ctx context.Context, p *EnclavePool, signer common.Address, f func(client enclave.RPC) (E, error)) (E, error) {
+	var empty E
+	known := false
+	reported := true
+	for _, b := range p.backends {
+		healthy, backendSigner := b.status()
+		if backendSigner == (common.Address{}) {
+			// never probed successfully
+			reported = false
+			continue
+		}
+		if backendSigner != signer {
+			continue
+		}
+		known = true
+		if !healthy {
+			continue
+		}
+		output, err := f(b.client)
+		if err != nil && isConnectionError(ctx, err) {
+			p.log.Warn("Enclave request failed, trying next enclave", "url", b.url, "err", err)
+			b.markUnhealthy()
+			continue
+		}
+		return output, err
+	}
+	if !known && reported {
+		return empty, fmt.Errorf("%w: %s", ErrUnknownSigner, signer)
+	}
+	return empty, fmt.Errorf("%w with signer %s", ErrNoHealthyEnclave, signer)
+}
+
+// proposalsSigner recovers the signer of a chain of proposals, and checks that all proposals share the same signer.
+func proposalsSigner(configHash common.Hash, prevOutputRoot common.Hash, proposals []*enclave.Proposal) (common.Address, error) {
+	var signer common.Address
+	outputRoot := prevOutputRoot
+	for i, proposal := range proposals {
+		hash := enclave.ProposalHash(configHash, proposal.L1OriginHash, proposal.L2BlockNumber.ToInt(), outputRoot, proposal.OutputRoot)
+		pub, err := crypto.SigToPub(hash[:], proposal.Signature)
+		if err != nil {
+			return common.Address{}, fmt.Errorf("failed to recover proposal signer: %w", err)
+		}
+		address := crypto.PubkeyToAddress(*pub)
+		if i > 0 && address != signer {
+			return common.Address{}, fmt.Errorf("%w: proposals have different signers: %s != %s", ErrUnknownSigner, address, signer)
+		}
+		signer = address
+		outputRoot = proposal.OutputRoot
+	}
+	return signer, nil
+}
@@ -0,0 +1,113 @@
+package proposer
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"errors"
+	"fmt"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/stretchr/testify/require"
+)
+
+// testEnclave is an enclave backend that reports the given signer, or fails with err.
+type testEnclave struct {
+	enclave.RPC
+	signer common.Address
+	err    error
+}
+
+func (e *testEnclave) Status(ctx context.Context) (*enclave.Status, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return &enclave.Status{SignerAddress: e.signer}, nil
+}
+
+func (e *testEnclave) Aggregate(ctx context.Context, configHash common.Hash, prevOutputRoot common.Hash, proposals []*enclave.Proposal) (*enclave.Proposal, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return proposals[len(proposals)-1], nil
+}
+
+func newTestPool(t *testing.T, backends ...*testEnclave) *EnclavePool {
+	t.Helper()
+	urls := make([]string, len(backends))
+	clients := make([]enclave.RPC, len(backends))
+	for i, b := range backends {
+		urls[i] = fmt.Sprintf("http://enclave-%d", i)
+		clients[i] = b
+	}
+	pool := NewEnclavePool(log.New(), urls, clients, time.Hour, time.Second)
+	pool.Start(context.Background())
+	t.Cleanup(pool.Close)
+	return pool
+}
+
+// signedProposals returns a chain of proposals starting after prevOutputRoot, signed by key.
+func signedProposals(t *testing.T, key *ecdsa.PrivateKey, configHash common.Hash, prevOutputRoot common.Hash, count int) []*enclave.Proposal {
+	t.Helper()
+	proposals := make([]*enclave.Proposal, count)
+	for i := range proposals {
+		number := big.NewInt(int64(i + 1))
+		outputRoot := common.BigToHash(number)
+		hash := enclave.ProposalHash(configHash, common.Hash{0x01}, number, prevOutputRoot, outputRoot)
+		sig, err := crypto.Sign(hash[:], key)
+		require.NoError(t, err)
+		proposals[i] = &enclave.Proposal{
+			OutputRoot:    outputRoot,
+			Signature:     sig,
+			L1OriginHash:  common.Hash{0x01},
+			L2BlockNumber: (*hexutil.Big)(number),
+		}
+		prevOutputRoot = outputRoot
+	}
+	return proposals
+}
+
+func TestEnclavePoolAggregateSigner(t *testing.T) {
+	ctx := context.Background()
+	key, err := crypto.GenerateKey()
+	require.NoError(t, err)
+	signer := crypto.PubkeyToAddress(key.PublicKey)
+	other := common.Address{0x02}
+	unreachable := errors.New("connection refused")
+	proposals := signedProposals(t, key, common.Hash{}, common.Hash{}, 2)
+
+	t.Run("holding the key", func(t *testing.T) {
+		pool := newTestPool(t, &testEnclave{signer: other}, &testEnclave{signer: signer})
+		_, err := pool.Aggregate(ctx, common.Hash{}, common.Hash{}, proposals)
+		require.NoError(t, err)
+	})
+
+	t.Run("never reached", func(t *testing.T) {
+		// a backend that hasn't reported its signer yet could still hold the key
+		pool := newTestPool(t, &testEnclave{signer: other}, &testEnclave{err: unreachable})
+		_, err := pool.Aggregate(ctx, common.Hash{}, common.Hash{}, proposals)
+		require.ErrorIs(t, err, ErrNoHealthyEnclave)
+		require.NotErrorIs(t, err, ErrUnknownSigner)
+	})
+
+	t.Run("unhealthy", func(t *testing.T) {
+		backend := &testEnclave{signer: signer}
+		pool := newTestPool(t, backend)
+		backend.err = unreachable
+		pool.probe(ctx)
+		_, err := pool.Aggregate(ctx, common.Hash{}, common.Hash{}, proposals)
+		require.ErrorIs(t, err, ErrNoHealthyEnclave)
+	})
+
+	t.Run("unknown", func(t *testing.T) {
+		pool := newTestPool(t, &testEnclave{signer: other}, &testEnclave{signer: common.Address{0x03}})
+		_, err := pool.Aggregate(ctx, common.Hash{}, common.Hash{}, proposals)
+		require.ErrorIs(t, err, ErrUnknownSigner)
+	})
+}
@@ -3,8 +3,11 @@ package proposer
 import (
 	"context"
 	"fmt"
+	"math/big"
+	"slices"
 
 	"github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/ethereum-optimism/optimism/op-node/rollup"
 	"github.com/ethereum-optimism/optimism/op-node/rollup/derive"
 	"github.com/ethereum-optimism/optimism/op-service/eth"
 	"github.com/ethereum-optimism/optimism/op-service/predeploys"
@@ -12,9 +15,14 @@ import (
 	"github.com/ethereum/go-ethereum/common/hexutil"
 	"github.com/ethereum/go-ethereum/core/stateless"
 	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/log"
 	"github.com/hashicorp/go-multierror"
+	"golang.org/x/sync/errgroup"
 )
 
+// anchorFetchConcurrency is the maximum number of L1 headers fetched concurrently when anchoring
+const anchorFetchConcurrency = 16
+
 type Prover struct {
 	config     *enclave.PerChainConfig
 	configHash common.Hash
@@ -28,6 +36,17 @@ type Proposal struct {
 	From        eth.L2BlockRef
 	To          eth.L2BlockRef
 	Withdrawals bool
+	// L1Anchor is the L1 block that the output is signed against, if it was anchored to a
+	// descendant of the L1 origin of To.
+	L1Anchor *eth.BlockID `json:",omitempty"`
+}
+
+// L1Block returns the L1 block that the output is signed against, which is checked onchain.
+func (p *Proposal) L1Block() eth.BlockID {
+	if p.L1Anchor != nil {
+		return *p.L1Anchor
+	}
+	return p.To.L1Origin
 }
 
 func NewProver(
@@ -36,23 +55,167 @@ func NewProver(
 	l2 L2Client,
 	rollup RollupClient,
 	enclav enclave.RPC,
+	configHash common.Hash,
 ) (*Prover, error) {
 	rollupConfig, err := rollup.RollupConfig(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch rollup config: %w", err)
 	}
-	cfg := enclave.FromRollupConfig(rollupConfig)
+	cfg, err := configForHash(rollupConfig, configHash)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Version > 0 {
+		if err = checkConfigVersion(ctx, enclav, cfg.Version); err != nil {
+			return nil, err
+		}
+	} else if cfg.BlockTime != rollupConfig.BlockTime {
+		log.Warn("Output oracle commits to a version 0 config, which executes with a fixed block time",
+			"block_time", cfg.BlockTime, "rollup_block_time", rollupConfig.BlockTime)
+	}
+	log.Info("Using chain config", "version", cfg.Version, "hash", configHash)
 
 	return &Prover{
 		config:     cfg,
-		configHash: cfg.Hash(),
+		configHash: configHash,
 		l1:         l1,
 		l2:         l2,
 		enclave:    enclav,
 	}, nil
 }
 
+// configForHash returns the config for the rollup config whose hash is the config hash that the
+// output oracle was deployed with, trying the newest version first.
+func configForHash(rollupConfig *rollup.Config, configHash common.Hash) (*enclave.PerChainConfig, error) {
+	var unsupported error
+	for _, version := range []uint64{1, 0} {
+		cfg, err := enclave.FromRollupConfig(rollupConfig, version)
+		if err != nil {
+			// version 0 configs ignore the rollup config's forks, so they may still match
+			unsupported = err
+			continue
+		}
+		if cfg.Hash() == configHash {
+			return cfg, nil
+		}
+	}
+	if unsupported != nil {
+		return nil, fmt.Errorf("rollup config does not match the output oracle's config hash %s: %w", configHash, unsupported)
+	}
+	return nil, fmt.Errorf("rollup config does not match the output oracle's config hash %s", configHash)
+}
+
+// checkConfigVersion returns an error if the enclave reports that it doesn't support the config
+// version. Enclaves that don't report their status are assumed to support it, since proposals
+// signed with a different config hash are rejected when they are aggregated.
+func checkConfigVersion(ctx context.Context, enclav enclave.RPC, version uint64) error {
+	status, err := enclav.Status(ctx)
+	if err != nil {
+		log.Warn("Unable to check the config versions supported by the enclave", "err", err)
+		return nil
+	}
+	if !slices.Contains(status.ConfigVersions, hexutil.Uint64(version)) {
+		return fmt.Errorf("enclave does not support config version %d, supported: %v", version, status.ConfigVersions)
+	}
+	return nil
+}
+
+// SupportsRange returns true if the enclave can execute a range of blocks in a single call.
+func (o *Prover) SupportsRange(ctx context.Context) (bool, error) {
+	capabilities, err := o.enclave.Capabilities(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch enclave capabilities: %w", err)
+	}
+	return slices.Contains(capabilities, enclave.CapabilityExecuteStatelessRange), nil
+}
+
+// SupportsAnchor returns true if the enclave can anchor a proposal to a descendant of its L1 origin.
+func (o *Prover) SupportsAnchor(ctx context.Context) (bool, error) {
+	capabilities, err := o.enclave.Capabilities(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to fetch enclave capabilities: %w", err)
+	}
+	return slices.Contains(capabilities, enclave.CapabilityAnchorProposal), nil
+}
+
 func (o *Prover) Generate(ctx context.Context, block *types.Block) (*Proposal, error) {
+	input, blockRef, err := o.prepare(ctx, block)
+	if err != nil {
+		return nil, err
+	}
+
+	output, err := o.enclave.ExecuteStateless(
+		ctx,
+		o.config,
+		input.L1Origin,
+		input.L1Receipts,
+		input.PreviousBlockTxs,
+		input.BlockHeader,
+		input.SequencedTxs,
+		input.Witness,
+		input.MessageAccount,
+		input.PrevMessageAccountHash,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute enclave state transition: %w", err)
+	}
+	if err = checkOutput(output, block, blockRef, input.MessageAccount.StorageHash); err != nil {
+		return nil, err
+	}
+
+	return &Proposal{
+		Output:      output,
+		From:        blockRef,
+		To:          blockRef,
+		Withdrawals: hasWithdrawals(block),
+	}, nil
+}
+
+// GenerateRange proves a contiguous range of blocks with a single enclave call.
+// Only use this if SupportsRange returns true.
+func (o *Prover) GenerateRange(ctx context.Context, blocks []*types.Block) (*Proposal, error) {
+	if len(blocks) == 0 {
+		return nil, fmt.Errorf("no blocks to prove")
+	}
+	if len(blocks) == 1 {
+		return o.Generate(ctx, blocks[0])
+	}
+
+	inputs := make([]*enclave.StatelessBlock, len(blocks))
+	refs := make([]eth.L2BlockRef, len(blocks))
+	withdrawals := false
+	for i, block := range blocks {
+		if i > 0 && block.ParentHash() != blocks[i-1].Hash() {
+			return nil, fmt.Errorf("block %d is not a child of block %d", block.NumberU64(), blocks[i-1].NumberU64())
+		}
+		input, blockRef, err := o.prepare(ctx, block)
+		if err != nil {
+			return nil, err
+		}
+		inputs[i] = input
+		refs[i] = blockRef
+		withdrawals = withdrawals || hasWithdrawals(block)
+	}
+
+	output, err := o.enclave.ExecuteStatelessRange(ctx, o.config, inputs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute enclave state transition range: %w", err)
+	}
+	last := len(blocks) - 1
+	if err = checkOutput(output, blocks[last], refs[last], inputs[last].MessageAccount.StorageHash); err != nil {
+		return nil, err
+	}
+
+	return &Proposal{
+		Output:      output,
+		From:        refs[0],
+		To:          refs[last],
+		Withdrawals: withdrawals,
+	}, nil
+}
+
+// prepare fetches all the inputs required for the enclave to statelessly execute the block.
+func (o *Prover) prepare(ctx context.Context, block *types.Block) (*enclave.StatelessBlock, eth.L2BlockRef, error) {
 	witnessCh := await(func() (*stateless.ExecutionWitness, error) {
 		return o.l2.ExecutionWitness(ctx, block.Hash())
 	}, func(err error) error {
@@ -79,7 +242,7 @@ func (o *Prover) Generate(ctx context.Context, block *types.Block) (*Proposal, e
 
 	blockRef, err := derive.L2BlockToBlockRef(o.config.ToRollupConfig(), block)
 	if err != nil {
-		return nil, fmt.Errorf("failed to derive block ref from L2 block: %w", err)
+		return nil, eth.L2BlockRef{}, fmt.Errorf("failed to derive block ref from L2 block: %w", err)
 	}
 
 	l1OriginCh := await(func() (*types.Header, error) {
@@ -115,7 +278,7 @@ func (o *Prover) Generate(ctx context.Context, block *types.Block) (*Proposal, e
 	errors = appendNonNil(errors, prevMessageAccount.err)
 
 	if len(errors) > 0 {
-		return nil, &multierror.Error{Errors: errors}
+		return nil, eth.L2BlockRef{}, &multierror.Error{Errors: errors}
 	}
 
 	marshalTxs := func(txs types.Transactions, includeDeposits bool) ([]hexutil.Bytes, error) {
@@ -134,46 +297,43 @@ func (o *Prover) Generate(ctx context.Context, block *types.Block) (*Proposal, e
 	}
 	previousTxs, err := marshalTxs(previousBlock.value.Transactions(), true)
 	if err != nil {
-		return nil, err
+		return nil, eth.L2BlockRef{}, err
 	}
 	sequencedTxs, err := marshalTxs(block.Transactions(), false)
 	if err != nil {
-		return nil, err
+		return nil, eth.L2BlockRef{}, err
 	}
 
-	output, err := o.enclave.ExecuteStateless(
-		ctx,
-		o.config,
-		l1Origin.value,
-		l1Receipts.value,
-		previousTxs,
-		block.Header(),
-		sequencedTxs,
-		witness.value,
-		messageAccount.value,
-		prevMessageAccount.value.StorageHash,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to execute enclave state transition: %w", err)
-	}
+	return &enclave.StatelessBlock{
+		L1Origin:               l1Origin.value,
+		L1Receipts:             l1Receipts.value,
+		PreviousBlockTxs:       previousTxs,
+		BlockHeader:            block.Header(),
+		SequencedTxs:           sequencedTxs,
+		Witness:                witness.value,
+		MessageAccount:         messageAccount.value,
+		PrevMessageAccountHash: prevMessageAccount.value.StorageHash,
+	}, blockRef, nil
+}
+
+// checkOutput validates that the enclave output matches the expected values for the given block.
+func checkOutput(output *enclave.Proposal, block *types.Block, blockRef eth.L2BlockRef, messageAccountHash common.Hash) error {
 	if output.L1OriginHash != blockRef.L1Origin.Hash {
-		return nil, fmt.Errorf("output L1 origin hash does not match expected: %s != %s", output.L1OriginHash, blockRef.L1Origin.Hash)
+		return fmt.Errorf("output L1 origin hash does not match expected: %s != %s", output.L1OriginHash, blockRef.L1Origin.Hash)
 	}
 	if output.L2BlockNumber.ToInt().Cmp(block.Number()) != 0 {
-		return nil, fmt.Errorf("output L2 block number does not match expected: %s != %s", output.L2BlockNumber, block.Number())
+		return fmt.Errorf("output L2 block number does not match expected: %s != %s", output.L2BlockNumber, block.Number())
 	}
 
-	outputRoot := enclave.OutputRootV0(block.Header(), messageAccount.value.StorageHash)
+	outputRoot := enclave.OutputRootV0(block.Header(), messageAccountHash)
 	if output.OutputRoot != outputRoot {
-		return nil, fmt.Errorf("output root does not match expected: %s != %s", output.OutputRoot, outputRoot)
+		return fmt.Errorf("output root does not match expected: %s != %s", output.OutputRoot, outputRoot)
 	}
+	return nil
+}
 
-	return &Proposal{
-		Output:      output,
-		From:        blockRef,
-		To:          blockRef,
-		Withdrawals: block.Bloom().Test(predeploys.L2ToL1MessagePasserAddr.Bytes()),
-	}, nil
+func hasWithdrawals(block *types.Block) bool {
+	return block.Bloom().Test(predeploys.L2ToL1MessagePasserAddr.Bytes())
 }
 
 func (o *Prover) Aggregate(ctx context.Context, prevOutputRoot common.Hash, proposals []*Proposal) (*Proposal, error) {
@@ -201,6 +361,74 @@ func (o *Prover) Aggregate(ctx context.Context, prevOutputRoot common.Hash, prop
 	}, nil
 }
 
+// Anchor re-signs the proposal against the L1 block with the given number, which must be a
+// descendant of the L1 block that the proposal is signed against. An anchored proposal can be
+// anchored again, which only needs the headers after its current anchor. Only use this if
+// SupportsAnchor returns true.
+func (o *Prover) Anchor(ctx context.Context, prevOutputRoot common.Hash, proposal *Proposal, anchor uint64) (*Proposal, error) {
+	origin := proposal.L1Block()
+	if anchor <= origin.Number {
+		return nil, fmt.Errorf("anchor block %d is not after L1 block %d", anchor, origin.Number)
+	}
+	if anchor-origin.Number > enclave.MaxAnchorHeaders {
+		return nil, fmt.Errorf("anchor block %d is more than %d blocks after L1 block %d", anchor, enclave.MaxAnchorHeaders, origin.Number)
+	}
+
+	headers, err := o.fetchL1Headers(ctx, origin.Number+1, anchor)
+	if err != nil {
+		return nil, err
+	}
+	parentHash := origin.Hash
+	for _, header := range headers {
+		if header.ParentHash != parentHash {
+			return nil, fmt.Errorf("L1 header %d is not a child of block %s, possible reorg", header.Number, parentHash)
+		}
+		parentHash = header.Hash()
+	}
+
+	output, err := o.enclave.AnchorProposal(ctx, o.configHash, prevOutputRoot, proposal.Output, headers)
+	if err != nil {
+		return nil, fmt.Errorf("failed to anchor proposal: %w", err)
+	}
+	if output.L1OriginHash != parentHash {
+		return nil, fmt.Errorf("output L1 anchor hash does not match expected: %s != %s", output.L1OriginHash, parentHash)
+	}
+	if output.OutputRoot != proposal.Output.OutputRoot {
+		return nil, fmt.Errorf("anchored output root does not match: %s != %s", output.OutputRoot, proposal.Output.OutputRoot)
+	}
+
+	return &Proposal{
+		Output:      output,
+		From:        proposal.From,
+		To:          proposal.To,
+		Withdrawals: proposal.Withdrawals,
+		L1Anchor:    &eth.BlockID{Hash: parentHash, Number: anchor},
+	}, nil
+}
+
+// fetchL1Headers fetches the L1 headers from start to end inclusive, using up to
+// anchorFetchConcurrency concurrent requests.
+func (o *Prover) fetchL1Headers(ctx context.Context, start uint64, end uint64) ([]*types.Header, error) {
+	headers := make([]*types.Header, end-start+1)
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(anchorFetchConcurrency)
+	for i := range headers {
+		number := start + uint64(i)
+		g.Go(func() error {
+			header, err := o.l1.HeaderByNumber(ctx, new(big.Int).SetUint64(number))
+			if err != nil {
+				return fmt.Errorf("failed to fetch L1 header %d: %w", number, err)
+			}
+			headers[i] = header
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+	return headers, nil
+}
+
 type result[E any] struct {
 	value E
 	err   error
@@ -11,13 +11,13 @@ import (
 	"github.com/base/op-enclave/op-enclave/enclave"
 	"github.com/base/op-enclave/op-proposer/metrics"
 	"github.com/ethereum-optimism/optimism/op-proposer/proposer/rpc"
-	opservice "github.com/ethereum-optimism/optimism/op-service"
 	"github.com/ethereum-optimism/optimism/op-service/cliapp"
 	"github.com/ethereum-optimism/optimism/op-service/dial"
 	"github.com/ethereum-optimism/optimism/op-service/httputil"
 	opmetrics "github.com/ethereum-optimism/optimism/op-service/metrics"
 	"github.com/ethereum-optimism/optimism/op-service/oppprof"
 	oprpc "github.com/ethereum-optimism/optimism/op-service/rpc"
+	"github.com/ethereum-optimism/optimism/op-service/signer"
 	"github.com/ethereum-optimism/optimism/op-service/txmgr"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/ethclient"
@@ -46,6 +46,9 @@ type ProposerConfig struct {
 	WaitNodeSync bool
 
 	MinProposalInterval uint64
+
+	// Maximum number of blocks (or block ranges) proven concurrently
+	ProofConcurrency int
 }
 
 type ProposerService struct {
@@ -54,14 +57,12 @@ type ProposerService struct {
 
 	ProposerConfig
 
-	TxManager     txmgr.TxManager
-	L1Client      *ethclient.Client
-	L2Client      *ethclient.Client
-	L2Reth        bool
-	RollupClient  *gethrpc.Client
-	EnclaveClient *gethrpc.Client
+	L1Client       *ethclient.Client
+	EnclaveClients []*gethrpc.Client
+	EnclavePool    *EnclavePool
 
-	driver *L2OutputSubmitter
+	// Chains are the L2 chains that outputs are proposed for, which share the L1 client and enclaves.
+	Chains []*ChainService
 
 	Version string
 
@@ -69,11 +70,25 @@ type ProposerService struct {
 	metricsSrv   *httputil.HTTPServer
 	rpcServer    *oprpc.Server
 
-	balanceMetricer io.Closer
-
 	stopped atomic.Bool
 }
 
+// ChainService holds the clients and driver of one of the chains that outputs are proposed for.
+type ChainService struct {
+	*ChainConfig
+
+	Log     log.Logger
+	Metrics *metrics.Metrics
+
+	TxManager     txmgr.TxManager
+	L2Client      *ethclient.Client
+	RollupClient  *gethrpc.Client
+	ProposalStore ProposalStore
+
+	driver          *L2OutputSubmitter
+	balanceMetricer io.Closer
+}
+
 // ProposerServiceFromCLIConfig creates a new ProposerService from a CLIConfig.
 // The service components are fully started, except for the driver,
 // which will not be submitting state (if it was configured to) until the Start part of the lifecycle.
@@ -96,14 +111,35 @@ func (ps *ProposerService) initFromCLIConfig(ctx context.Context, version string
 	ps.AllowNonFinalized = cfg.AllowNonFinalized
 	ps.WaitNodeSync = cfg.WaitNodeSync
 	ps.MinProposalInterval = cfg.MinProposalInterval
+	ps.ProofConcurrency = cfg.ProofConcurrency
 
-	ps.initL2ooAddress(cfg)
-
+	chains, err := cfg.Chains()
+	if err != nil {
+		return err
+	}
 	if err := ps.initRPCClients(ctx, cfg); err != nil {
 		return err
 	}
-	if err := ps.initTxManager(cfg); err != nil {
-		return fmt.Errorf("failed to init Tx manager: %w", err)
+	for _, chainCfg := range chains {
+		chain := &ChainService{
+			ChainConfig: chainCfg,
+			Log:         ps.Log,
+		}
+		if cfg.ChainsFile != "" {
+			chain.Log = ps.Log.New("chain", chainCfg.Name)
+			chain.Metrics = ps.Metrics.ForChain(chainCfg.Name)
+		} else {
+			// a single chain keeps the unlabeled metrics of a single-chain proposer
+			chain.Metrics = ps.Metrics.ForChain("")
+		}
+		// added before initializing, so that a partially initialized chain is cleaned up by Stop
+		ps.Chains = append(ps.Chains, chain)
+		if err := chain.init(ctx, cfg); err != nil {
+			return fmt.Errorf("failed to init chain %s: %w", chainCfg.Name, err)
+		}
+	}
+	if err := ps.checkAccounts(); err != nil {
+		return err
 	}
 	ps.initBalanceMonitor(cfg)
 	if err := ps.initMetricsServer(cfg); err != nil {
@@ -112,7 +148,7 @@ func (ps *ProposerService) initFromCLIConfig(ctx context.Context, version string
 	if err := ps.initPProf(cfg); err != nil {
 		return fmt.Errorf("failed to init profiling: %w", err)
 	}
-	if err := ps.initDriver(); err != nil {
+	if err := ps.initDrivers(); err != nil {
 		return fmt.Errorf("failed to init Driver: %w", err)
 	}
 	if err := ps.initRPCServer(cfg); err != nil {
@@ -131,25 +167,70 @@ func (ps *ProposerService) initRPCClients(ctx context.Context, cfg *CLIConfig) e
 	}
 	ps.L1Client = l1Client
 
-	l2Client, err := dial.DialEthClientWithTimeout(ctx, dial.DefaultDialTimeout, ps.Log, cfg.L2EthRpc)
+	if len(cfg.EnclaveRpcs) == 0 {
+		return errors.New("no enclave RPC configured")
+	}
+	enclaveClients := make([]enclave.RPC, len(cfg.EnclaveRpcs))
+	for i, url := range cfg.EnclaveRpcs {
+		enclaveClient, err := dial.DialRPCClientWithTimeout(ctx, dial.DefaultDialTimeout, ps.Log, url)
+		if err != nil {
+			return fmt.Errorf("failed to dial enclave RPC %s: %w", url, err)
+		}
+		ps.EnclaveClients = append(ps.EnclaveClients, enclaveClient)
+		enclaveClients[i] = &enclave.Client{Client: enclaveClient}
+	}
+	ps.EnclavePool = NewEnclavePool(ps.Log, cfg.EnclaveRpcs, enclaveClients, cfg.EnclaveHealthInterval, cfg.TxMgrConfig.NetworkTimeout)
+	ps.EnclavePool.Start(ctx)
+
+	return nil
+}
+
+// init dials the chain's L2 and rollup RPCs, and opens its proposal store and tx manager.
+func (c *ChainService) init(ctx context.Context, cfg *CLIConfig) error {
+	l2Client, err := dial.DialEthClientWithTimeout(ctx, dial.DefaultDialTimeout, c.Log, c.L2EthRpc)
 	if err != nil {
 		return fmt.Errorf("failed to dial L2 RPC: %w", err)
 	}
-	ps.L2Client = l2Client
-	ps.L2Reth = cfg.L2Reth
+	c.L2Client = l2Client
 
-	rollupClient, err := dial.DialRPCClientWithTimeout(ctx, dial.DefaultDialTimeout, ps.Log, cfg.RollupRpc)
+	rollupClient, err := dial.DialRPCClientWithTimeout(ctx, dial.DefaultDialTimeout, c.Log, c.RollupRpc)
 	if err != nil {
 		return fmt.Errorf("failed to dial L2 rollup RPC: %w", err)
 	}
-	ps.RollupClient = rollupClient
+	c.RollupClient = rollupClient
 
-	enclaveClient, err := dial.DialRPCClientWithTimeout(ctx, dial.DefaultDialTimeout, ps.Log, cfg.EnclaveRpc)
+	store, err := OpenProposalStore(cfg.PendingStore, c.PendingStorePath)
 	if err != nil {
-		return fmt.Errorf("failed to dial enclave RPC: %w", err)
+		return fmt.Errorf("failed to init proposal store: %w", err)
 	}
-	ps.EnclaveClient = enclaveClient
+	c.ProposalStore = store
 
+	txMgrConfig := cfg.TxMgrConfig
+	if c.PrivateKey != "" {
+		txMgrConfig.PrivateKey = c.PrivateKey
+		txMgrConfig.Mnemonic = ""
+		txMgrConfig.HDPath = ""
+		txMgrConfig.SignerCLIConfig = signer.NewCLIConfig()
+	}
+	txManager, err := txmgr.NewSimpleTxManager("proposer", c.Log, c.Metrics, txMgrConfig)
+	if err != nil {
+		return fmt.Errorf("failed to init Tx manager: %w", err)
+	}
+	c.TxManager = txManager
+	return nil
+}
+
+// checkAccounts returns an error if chains share a proposer account, since their tx managers
+// would use conflicting nonces.
+func (ps *ProposerService) checkAccounts() error {
+	accounts := make(map[common.Address]string)
+	for _, chain := range ps.Chains {
+		from := chain.TxManager.From()
+		if other, ok := accounts[from]; ok {
+			return fmt.Errorf("chains %s and %s use the same proposer account %s", other, chain.Name, from)
+		}
+		accounts[from] = chain.Name
+	}
 	return nil
 }
 
@@ -158,20 +239,13 @@ func (ps *ProposerService) initMetrics(cfg *CLIConfig) {
 	ps.Metrics = metrics.NewMetrics(procName)
 }
 
-// initBalanceMonitor depends on Metrics, L1Client and TxManager to start background-monitoring of the Proposer balance.
+// initBalanceMonitor depends on Metrics, L1Client and the chains' TxManagers to start background-monitoring of the Proposer balances.
 func (ps *ProposerService) initBalanceMonitor(cfg *CLIConfig) {
 	if cfg.MetricsConfig.Enabled {
-		ps.balanceMetricer = ps.Metrics.StartBalanceMetrics(ps.Log, ps.L1Client, ps.TxManager.From())
-	}
-}
-
-func (ps *ProposerService) initTxManager(cfg *CLIConfig) error {
-	txManager, err := txmgr.NewSimpleTxManager("proposer", ps.Log, ps.Metrics, cfg.TxMgrConfig)
-	if err != nil {
-		return err
+		for _, chain := range ps.Chains {
+			chain.balanceMetricer = chain.Metrics.StartBalanceMetrics(chain.Log, ps.L1Client, chain.TxManager.From())
+		}
 	}
-	ps.TxManager = txManager
-	return nil
 }
 
 func (ps *ProposerService) initPProf(cfg *CLIConfig) error {
@@ -206,36 +280,33 @@ func (ps *ProposerService) initMetricsServer(cfg *CLIConfig) error {
 	return nil
 }
 
-func (ps *ProposerService) initL2ooAddress(cfg *CLIConfig) {
-	l2ooAddress, err := opservice.ParseAddress(cfg.L2OOAddress)
-	if err != nil {
-		// Return no error & set no L2OO related configuration fields.
-		return
-	}
-	ps.L2OutputOracleAddr = &l2ooAddress
-}
-
-func (ps *ProposerService) initDriver() error {
-	var l2Client L2Client
-	if ps.L2Reth {
-		l2Client = NewRethClient(ps.L2Client, ps.Metrics.L2Cache)
-	} else {
-		l2Client = NewClient(ps.L2Client, ps.Metrics.L2Cache)
-	}
-	driver, err := NewL2OutputSubmitter(DriverSetup{
-		Log:           ps.Log,
-		Metr:          ps.Metrics,
-		Cfg:           ps.ProposerConfig,
-		Txmgr:         ps.TxManager,
-		L1Client:      NewClient(ps.L1Client, ps.Metrics.L1Cache),
-		L2Client:      l2Client,
-		RollupClient:  NewRollupClient(ps.RollupClient, ps.Metrics.WitnessCache),
-		EnclaveClient: &enclave.Client{Client: ps.EnclaveClient},
-	})
-	if err != nil {
-		return err
+func (ps *ProposerService) initDrivers() error {
+	l1Client := NewClient(ps.L1Client, ps.Metrics.L1Cache)
+	for _, chain := range ps.Chains {
+		var l2Client L2Client
+		if chain.L2Reth {
+			l2Client = NewRethClient(chain.L2Client, chain.Metrics.L2Cache)
+		} else {
+			l2Client = NewClient(chain.L2Client, chain.Metrics.L2Cache)
+		}
+		proposerConfig := ps.ProposerConfig
+		proposerConfig.L2OutputOracleAddr = &chain.L2OOAddress
+		driver, err := NewL2OutputSubmitter(DriverSetup{
+			Log:           chain.Log,
+			Metr:          chain.Metrics,
+			Cfg:           proposerConfig,
+			Txmgr:         chain.TxManager,
+			L1Client:      l1Client,
+			L2Client:      l2Client,
+			RollupClient:  NewRollupClient(chain.RollupClient, chain.Metrics.WitnessCache),
+			EnclaveClient: ps.EnclavePool,
+			Store:         chain.ProposalStore,
+		})
+		if err != nil {
+			return fmt.Errorf("chain %s: %w", chain.Name, err)
+		}
+		chain.driver = driver
 	}
-	ps.driver = driver
 	return nil
 }
 
@@ -247,9 +318,13 @@ func (ps *ProposerService) initRPCServer(cfg *CLIConfig) error {
 		oprpc.WithLogger(ps.Log),
 	)
 	if cfg.RPCConfig.EnableAdmin {
-		adminAPI := rpc.NewAdminAPI(ps.driver, ps.Metrics, ps.Log)
+		adminAPI := rpc.NewAdminAPI(ps.drivers(), ps.Metrics, ps.Log)
 		server.AddAPI(rpc.GetAdminAPI(adminAPI))
-		server.AddAPI(ps.TxManager.API())
+		if len(ps.Chains) == 1 {
+			// the txmgr namespace can only be served for a single tx manager
+			server.AddAPI(ps.Chains[0].TxManager.API())
+		}
+		server.AddAPI(GetEnclaveAdminAPI(NewEnclaveAdminAPI(ps.EnclavePool, ps.Log)))
 		ps.Log.Info("Admin RPC enabled")
 	}
 	ps.Log.Info("Starting JSON-RPC server")
@@ -263,8 +338,8 @@ func (ps *ProposerService) initRPCServer(cfg *CLIConfig) error {
 // Start runs once upon start of the proposer lifecycle,
 // and starts L2Output-submission work if the proposer is configured to start submit data on startup.
 func (ps *ProposerService) Start(_ context.Context) error {
-	ps.Log.Info("Starting Proposer")
-	return ps.driver.StartL2OutputSubmitting()
+	ps.Log.Info("Starting Proposer", "chains", len(ps.Chains))
+	return ps.drivers().StartL2OutputSubmitting()
 }
 
 func (ps *ProposerService) Stopped() bool {
@@ -287,9 +362,11 @@ func (ps *ProposerService) Stop(ctx context.Context) error {
 	ps.Log.Info("Stopping Proposer")
 
 	var result error
-	if ps.driver != nil {
-		if err := ps.driver.StopL2OutputSubmittingIfRunning(); err != nil {
-			result = errors.Join(result, fmt.Errorf("failed to stop L2Output submitting: %w", err))
+	for _, chain := range ps.Chains {
+		if chain.driver != nil {
+			if err := chain.driver.StopL2OutputSubmittingIfRunning(); err != nil {
+				result = errors.Join(result, fmt.Errorf("failed to stop L2Output submitting of chain %s: %w", chain.Name, err))
+			}
 		}
 	}
 
@@ -303,14 +380,11 @@ func (ps *ProposerService) Stop(ctx context.Context) error {
 			result = errors.Join(result, fmt.Errorf("failed to stop PProf server: %w", err))
 		}
 	}
-	if ps.balanceMetricer != nil {
-		if err := ps.balanceMetricer.Close(); err != nil {
-			result = errors.Join(result, fmt.Errorf("failed to close balance metricer: %w", err))
-		}
-	}
 
-	if ps.TxManager != nil {
-		ps.TxManager.Close()
+	for _, chain := range ps.Chains {
+		if err := chain.close(); err != nil {
+			result = errors.Join(result, fmt.Errorf("failed to close chain %s: %w", chain.Name, err))
+		}
 	}
 
 	if ps.metricsSrv != nil {
@@ -323,16 +397,12 @@ func (ps *ProposerService) Stop(ctx context.Context) error {
 		ps.L1Client.Close()
 	}
 
-	if ps.L2Client != nil {
-		ps.L2Client.Close()
+	if ps.EnclavePool != nil {
+		ps.EnclavePool.Close()
 	}
 
-	if ps.RollupClient != nil {
-		ps.RollupClient.Close()
-	}
-
-	if ps.EnclaveClient != nil {
-		ps.EnclaveClient.Close()
+	for _, enclaveClient := range ps.EnclaveClients {
+		enclaveClient.Close()
 	}
 
 	if result == nil {
@@ -345,8 +415,62 @@ func (ps *ProposerService) Stop(ctx context.Context) error {
 
 var _ cliapp.Lifecycle = (*ProposerService)(nil)
 
-// Driver returns the handler on the L2Output-submitter driver element,
+// close releases the chain's resources. Its driver must have been stopped.
+func (c *ChainService) close() error {
+	var result error
+	if c.balanceMetricer != nil {
+		if err := c.balanceMetricer.Close(); err != nil {
+			result = errors.Join(result, fmt.Errorf("failed to close balance metricer: %w", err))
+		}
+	}
+	if c.TxManager != nil {
+		c.TxManager.Close()
+	}
+	if c.L2Client != nil {
+		c.L2Client.Close()
+	}
+	if c.RollupClient != nil {
+		c.RollupClient.Close()
+	}
+	if c.ProposalStore != nil {
+		if err := c.ProposalStore.Close(); err != nil {
+			result = errors.Join(result, fmt.Errorf("failed to close proposal store: %w", err))
+		}
+	}
+	return result
+}
+
+// Driver returns the handler on the L2Output-submitter drivers of all chains,
 // to start/stop/restart the L2Output-submission work, for use in testing.
 func (ps *ProposerService) Driver() rpc.ProposerDriver {
-	return ps.driver
+	return ps.drivers()
+}
+
+func (ps *ProposerService) drivers() chainDrivers {
+	drivers := make(chainDrivers, 0, len(ps.Chains))
+	for _, chain := range ps.Chains {
+		if chain.driver != nil {
+			drivers = append(drivers, chain.driver)
+		}
+	}
+	return drivers
+}
+
+// chainDrivers starts and stops the drivers of all chains together.
+type chainDrivers []*L2OutputSubmitter
+
+func (d chainDrivers) StartL2OutputSubmitting() error {
+	var result error
+	for _, driver := range d {
+		result = errors.Join(result, driver.StartL2OutputSubmitting())
+	}
+	return result
+}
+
+func (d chainDrivers) StopL2OutputSubmitting() error {
+	var result error
+	for _, driver := range d {
+		result = errors.Join(result, driver.StopL2OutputSubmitting())
+	}
+	return result
 }
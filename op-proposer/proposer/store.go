@@ -0,0 +1,268 @@
+package proposer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/ethdb"
+	"github.com/ethereum/go-ethereum/ethdb/leveldb"
+	"github.com/ethereum/go-ethereum/ethdb/pebble"
+)
+
+const (
+	StoreTypeMemory  = "memory"
+	StoreTypeFile    = "file"
+	StoreTypePebble  = "pebble"
+	StoreTypeLevelDB = "leveldb"
+)
+
+// ProposalStore persists pending proposals, so that already generated proofs survive a proposer restart.
+type ProposalStore interface {
+	// Load returns all stored proposals, in the order they were stored.
+	Load() ([]*Proposal, error)
+	// Append stores a proposal after all existing proposals.
+	Append(proposal *Proposal) error
+	// Replace replaces all stored proposals.
+	Replace(proposals []*Proposal) error
+	Close() error
+}
+
+// OpenProposalStore opens a ProposalStore of the given type at the given path.
+func OpenProposalStore(storeType string, path string) (ProposalStore, error) {
+	if storeType != StoreTypeMemory && path == "" {
+		return nil, fmt.Errorf("path is required for %s proposal store", storeType)
+	}
+	switch storeType {
+	case StoreTypeMemory:
+		return memoryStore{}, nil
+	case StoreTypeFile:
+		return newFileStore(path)
+	case StoreTypePebble:
+		db, err := pebble.New(path, 16, 16, "", false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open pebble database: %w", err)
+		}
+		return &kvStore{db: db}, nil
+	case StoreTypeLevelDB:
+		db, err := leveldb.New(path, 16, 16, "", false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open leveldb database: %w", err)
+		}
+		return &kvStore{db: db}, nil
+	default:
+		return nil, fmt.Errorf("unknown proposal store type: %s", storeType)
+	}
+}
+
+// memoryStore doesn't persist anything; pending proposals are only kept in the driver.
+type memoryStore struct{}
+
+func (memoryStore) Load() ([]*Proposal, error) {
+	return nil, nil
+}
+
+func (memoryStore) Append(*Proposal) error {
+	return nil
+}
+
+func (memoryStore) Replace([]*Proposal) error {
+	return nil
+}
+
+func (memoryStore) Close() error {
+	return nil
+}
+
+// fileStore stores proposals as JSON lines in a single file. Appends are written
+// to the end of the file, and replacements atomically swap in a new file.
+type fileStore struct {
+	path string
+	file *os.File
+}
+
+func newFileStore(path string) (*fileStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create proposal store directory: %w", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_RDWR, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proposal store: %w", err)
+	}
+	return &fileStore{
+		path: path,
+		file: file,
+	}, nil
+}
+
+// Load reads the stored proposals. A partially written trailing line, which can be left behind by
+// a crash, is dropped along with everything after it, and truncated from the file so that later
+// appends start on a new line.
+func (f *fileStore) Load() ([]*Proposal, error) {
+	file, err := os.Open(f.path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open proposal store: %w", err)
+	}
+	defer func() {
+		_ = file.Close()
+	}()
+
+	var proposals []*Proposal
+	var offset int64
+	r := bufio.NewReader(file)
+	for {
+		line, err := r.ReadBytes('\n')
+		if errors.Is(err, io.EOF) {
+			// an unterminated line was not completely written
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read proposal store: %w", err)
+		}
+		var proposal Proposal
+		if err = json.Unmarshal(line, &proposal); err != nil {
+			break
+		}
+		proposals = append(proposals, &proposal)
+		offset += int64(len(line))
+	}
+
+	info, err := f.file.Stat()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read proposal store: %w", err)
+	}
+	if info.Size() > offset {
+		if err = f.file.Truncate(offset); err != nil {
+			return nil, fmt.Errorf("failed to truncate proposal store: %w", err)
+		}
+		if err = f.file.Sync(); err != nil {
+			return nil, fmt.Errorf("failed to truncate proposal store: %w", err)
+		}
+	}
+	return proposals, nil
+}
+
+func (f *fileStore) Append(proposal *Proposal) error {
+	line, err := json.Marshal(proposal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal proposal: %w", err)
+	}
+	if _, err = f.file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write proposal: %w", err)
+	}
+	return f.file.Sync()
+}
+
+func (f *fileStore) Replace(proposals []*Proposal) error {
+	tmp := f.path + ".tmp"
+	file, err := os.OpenFile(tmp, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to create proposal store: %w", err)
+	}
+	w := bufio.NewWriter(file)
+	enc := json.NewEncoder(w)
+	for _, proposal := range proposals {
+		if err = enc.Encode(proposal); err != nil {
+			break
+		}
+	}
+	if err == nil {
+		err = w.Flush()
+	}
+	if err == nil {
+		err = file.Sync()
+	}
+	err = errors.Join(err, file.Close())
+	if err != nil {
+		return fmt.Errorf("failed to write proposal store: %w", err)
+	}
+
+	if err = f.file.Close(); err != nil {
+		return fmt.Errorf("failed to close proposal store: %w", err)
+	}
+	if err = os.Rename(tmp, f.path); err != nil {
+		return fmt.Errorf("failed to replace proposal store: %w", err)
+	}
+	f.file, err = os.OpenFile(f.path, os.O_APPEND|os.O_RDWR, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open proposal store: %w", err)
+	}
+	return nil
+}
+
+func (f *fileStore) Close() error {
+	return f.file.Close()
+}
+
+var proposalKeyPrefix = []byte("proposal-")
+
+// kvStore stores proposals in a key-value database, keyed by the first block number of the proposal.
+type kvStore struct {
+	db ethdb.KeyValueStore
+}
+
+func proposalKey(proposal *Proposal) []byte {
+	return binary.BigEndian.AppendUint64(append([]byte{}, proposalKeyPrefix...), proposal.From.Number)
+}
+
+func (k *kvStore) Load() ([]*Proposal, error) {
+	it := k.db.NewIterator(proposalKeyPrefix, nil)
+	defer it.Release()
+
+	var proposals []*Proposal
+	for it.Next() {
+		var proposal Proposal
+		if err := json.Unmarshal(it.Value(), &proposal); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal proposal: %w", err)
+		}
+		proposals = append(proposals, &proposal)
+	}
+	if err := it.Error(); err != nil {
+		return nil, fmt.Errorf("failed to iterate proposals: %w", err)
+	}
+	return proposals, nil
+}
+
+func (k *kvStore) Append(proposal *Proposal) error {
+	value, err := json.Marshal(proposal)
+	if err != nil {
+		return fmt.Errorf("failed to marshal proposal: %w", err)
+	}
+	return k.db.Put(proposalKey(proposal), value)
+}
+
+func (k *kvStore) Replace(proposals []*Proposal) error {
+	batch := k.db.NewBatch()
+	it := k.db.NewIterator(proposalKeyPrefix, nil)
+	for it.Next() {
+		if err := batch.Delete(it.Key()); err != nil {
+			it.Release()
+			return err
+		}
+	}
+	err := it.Error()
+	it.Release()
+	if err != nil {
+		return fmt.Errorf("failed to iterate proposals: %w", err)
+	}
+
+	for _, proposal := range proposals {
+		value, err := json.Marshal(proposal)
+		if err != nil {
+			return fmt.Errorf("failed to marshal proposal: %w", err)
+		}
+		if err = batch.Put(proposalKey(proposal), value); err != nil {
+			return err
+		}
+	}
+	return batch.Write()
+}
+
+func (k *kvStore) Close() error {
+	return k.db.Close()
+}
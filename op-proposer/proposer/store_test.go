@@ -0,0 +1,134 @@
+package proposer
+
+import (
+	"encoding/json"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/ethereum-optimism/optimism/op-service/eth"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/stretchr/testify/require"
+)
+
+func testProposal(from uint64, to uint64) *Proposal {
+	return &Proposal{
+		Output: &enclave.Proposal{
+			OutputRoot:    common.BigToHash(new(big.Int).SetUint64(to)),
+			Signature:     make(hexutil.Bytes, 65),
+			L1OriginHash:  common.Hash{0x01},
+			L2BlockNumber: (*hexutil.Big)(new(big.Int).SetUint64(to)),
+		},
+		From:        eth.L2BlockRef{Number: from, Hash: common.Hash{byte(from)}},
+		To:          eth.L2BlockRef{Number: to, Hash: common.Hash{byte(to)}},
+		Withdrawals: to%2 == 0,
+	}
+}
+
+// requireProposals compares proposals by their JSON encoding, since decoded big integers
+// aren't necessarily deeply equal to the original ones.
+func requireProposals(t *testing.T, expected []*Proposal, actual []*Proposal) {
+	t.Helper()
+	expectedJSON, err := json.Marshal(expected)
+	require.NoError(t, err)
+	actualJSON, err := json.Marshal(actual)
+	require.NoError(t, err)
+	require.JSONEq(t, string(expectedJSON), string(actualJSON))
+}
+
+func TestProposalStore(t *testing.T) {
+	for _, storeType := range []string{StoreTypeFile, StoreTypePebble, StoreTypeLevelDB} {
+		t.Run(storeType, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "pending")
+			store, err := OpenProposalStore(storeType, path)
+			require.NoError(t, err)
+
+			proposals, err := store.Load()
+			require.NoError(t, err)
+			require.Empty(t, proposals)
+
+			expected := []*Proposal{testProposal(1, 100), testProposal(101, 200), testProposal(201, 201)}
+			for _, proposal := range expected {
+				require.NoError(t, store.Append(proposal))
+			}
+			proposals, err = store.Load()
+			require.NoError(t, err)
+			requireProposals(t, expected, proposals)
+
+			expected = []*Proposal{testProposal(1, 200), testProposal(201, 201)}
+			require.NoError(t, store.Replace(expected))
+			proposals, err = store.Load()
+			require.NoError(t, err)
+			requireProposals(t, expected, proposals)
+
+			// appends after a replacement go to the end of the new proposals
+			expected = append(expected, testProposal(202, 300))
+			require.NoError(t, store.Append(expected[2]))
+			require.NoError(t, store.Close())
+
+			store, err = OpenProposalStore(storeType, path)
+			require.NoError(t, err)
+			proposals, err = store.Load()
+			require.NoError(t, err)
+			requireProposals(t, expected, proposals)
+
+			require.NoError(t, store.Replace(nil))
+			proposals, err = store.Load()
+			require.NoError(t, err)
+			require.Empty(t, proposals)
+			require.NoError(t, store.Close())
+		})
+	}
+}
+
+func TestFileStoreDropsPartialLine(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pending")
+	store, err := OpenProposalStore(StoreTypeFile, path)
+	require.NoError(t, err)
+	expected := []*Proposal{testProposal(1, 100)}
+	require.NoError(t, store.Append(expected[0]))
+	require.NoError(t, store.Close())
+
+	// simulate a crash halfway through writing a proposal
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0o644)
+	require.NoError(t, err)
+	_, err = file.WriteString(`{"Output":{"OutputRoot":"0x`)
+	require.NoError(t, err)
+	require.NoError(t, file.Close())
+
+	store, err = OpenProposalStore(StoreTypeFile, path)
+	require.NoError(t, err)
+	proposals, err := store.Load()
+	require.NoError(t, err)
+	requireProposals(t, expected, proposals)
+
+	// the partial line is truncated, so proposals appended after recovery survive a restart
+	expected = append(expected, testProposal(101, 200))
+	require.NoError(t, store.Append(expected[1]))
+	require.NoError(t, store.Close())
+
+	store, err = OpenProposalStore(StoreTypeFile, path)
+	require.NoError(t, err)
+	defer store.Close()
+	proposals, err = store.Load()
+	require.NoError(t, err)
+	requireProposals(t, expected, proposals)
+}
+
+func TestOpenProposalStore(t *testing.T) {
+	_, err := OpenProposalStore(StoreTypeFile, "")
+	require.ErrorContains(t, err, "path is required")
+
+	_, err = OpenProposalStore("unknown", filepath.Join(t.TempDir(), "pending"))
+	require.ErrorContains(t, err, "unknown proposal store type")
+
+	store, err := OpenProposalStore(StoreTypeMemory, "")
+	require.NoError(t, err)
+	require.NoError(t, store.Append(testProposal(1, 1)))
+	proposals, err := store.Load()
+	require.NoError(t, err)
+	require.Empty(t, proposals)
+}
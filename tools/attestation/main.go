@@ -0,0 +1,259 @@
+package main
+
+import (
+	"context"
+	"crypto/x509"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/base/op-enclave/bindings"
+	"github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/base/op-enclave/tools/signers"
+	"github.com/ethereum-optimism/optimism/op-service/ctxinterrupt"
+	oplog "github.com/ethereum-optimism/optimism/op-service/log"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/hf/nitrite"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	AttestationFlag = &cli.StringFlag{
+		Name:  "attestation",
+		Usage: "Attestation hex, or a file containing the attestation (hex or binary)",
+	}
+	RootsFlag = &cli.StringFlag{
+		Name:  "roots",
+		Usage: "PEM file of root certificates to verify the attestation with (default: AWS Nitro root)",
+	}
+	VerifyTimeFlag = &cli.StringFlag{
+		Name:  "verify-time",
+		Usage: "Time to verify the attestation certificates at, as RFC3339 or unix seconds (default: now)",
+	}
+	RPCFlag = &cli.StringFlag{
+		Name:    "rpc",
+		Usage:   "URL of an L1 RPC host, for checking the deployment (optional)",
+		EnvVars: []string{"L1_URL"},
+	}
+	AddressFlag = &cli.StringFlag{
+		Name:    "address",
+		Usage:   "Address of the SystemConfigGlobal proxy contract, for checking the deployment (optional)",
+		EnvVars: []string{"SYSTEM_CONFIG_GLOBAL_ADDRESS"},
+	}
+)
+
+func main() {
+	oplog.SetupDefaults()
+
+	app := cli.NewApp()
+	app.Name = "attestation"
+	app.Usage = "Inspects op-enclave attestations"
+	app.Action = func(c *cli.Context) error {
+		return cli.ShowAppHelp(c)
+	}
+	app.Commands = []*cli.Command{
+		{
+			Name:      "inspect",
+			Usage:     "Verify an attestation and print its contents, without sending any transactions",
+			ArgsUsage: "[attestation]",
+			Action:    Inspect,
+			Flags: []cli.Flag{
+				AttestationFlag,
+				RootsFlag,
+				VerifyTimeFlag,
+				RPCFlag,
+				AddressFlag,
+			},
+		},
+	}
+
+	ctx := ctxinterrupt.WithSignalWaiterMain(context.Background())
+	err := app.RunContext(ctx, os.Args)
+	if err != nil {
+		log.Crit("Application failed", "message", err)
+	}
+}
+
+func Inspect(cliCtx *cli.Context) error {
+	input := cliCtx.String(AttestationFlag.Name)
+	if input == "" {
+		input = cliCtx.Args().First()
+	}
+	if input == "" {
+		return fmt.Errorf("missing attestation")
+	}
+	attestation, err := readAttestation(input)
+	if err != nil {
+		return err
+	}
+
+	opts, err := signers.VerifyOptions(cliCtx.String(RootsFlag.Name), cliCtx.String(VerifyTimeFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	res, verifyErr := enclave.VerifyAttestation(attestation, opts)
+	if res == nil {
+		return verifyErr
+	}
+	if verifyErr != nil {
+		fmt.Printf("Verification:   FAILED: %s\n", verifyErr)
+	} else {
+		fmt.Printf("Verification:   OK\n")
+	}
+	printDocument(res)
+
+	rpcUrl := cliCtx.String(RPCFlag.Name)
+	address := cliCtx.String(AddressFlag.Name)
+	if rpcUrl == "" || address == "" {
+		return verifyErr
+	}
+	if !common.IsHexAddress(address) {
+		return fmt.Errorf("invalid SystemConfigGlobal address: %s", address)
+	}
+	if err = printDeployment(cliCtx.Context, rpcUrl, common.HexToAddress(address), res); err != nil {
+		return err
+	}
+	return verifyErr
+}
+
+func printDocument(res *nitrite.Result) {
+	doc := res.Document
+	fmt.Printf("Module ID:      %s\n", doc.ModuleID)
+	fmt.Printf("Timestamp:      %s\n", time.UnixMilli(int64(doc.Timestamp)).UTC().Format(time.RFC3339))
+	fmt.Printf("Digest:         %s\n", doc.Digest)
+	if len(doc.PublicKey) > 0 {
+		fmt.Printf("Public key:     %s\n", hexutil.Encode(doc.PublicKey))
+		if signer, ok := signerAddress(doc.PublicKey); ok {
+			fmt.Printf("Signer address: %s\n", signer)
+		}
+	}
+	if len(doc.UserData) > 0 {
+		fmt.Printf("User data:      %s\n", hexutil.Encode(doc.UserData))
+	}
+	if len(doc.Nonce) > 0 {
+		fmt.Printf("Nonce:          %s\n", hexutil.Encode(doc.Nonce))
+	}
+
+	fmt.Println("PCRs:")
+	indexes := make([]uint, 0, len(doc.PCRs))
+	for index := range doc.PCRs {
+		indexes = append(indexes, index)
+	}
+	slices.Sort(indexes)
+	for _, index := range indexes {
+		pcr := doc.PCRs[index]
+		if index != 0 && isZero(pcr) {
+			continue
+		}
+		fmt.Printf("  PCR%-2d %s\n", index, hex.EncodeToString(pcr))
+	}
+	fmt.Printf("PCR0 hash:      %s\n", crypto.Keccak256Hash(doc.PCRs[0]))
+
+	fmt.Println("Certificates (root first):")
+	for i, der := range append(slices.Clone(doc.CABundle), doc.Certificate) {
+		cert, err := x509.ParseCertificate(der)
+		if err != nil {
+			fmt.Printf("  %d: %s (failed to parse: %s)\n", i, crypto.Keccak256Hash(der), err)
+			continue
+		}
+		fmt.Printf("  %d: %s %s (expires %s)\n", i, crypto.Keccak256Hash(der), cert.Subject.CommonName, cert.NotAfter.UTC().Format(time.RFC3339))
+	}
+}
+
+// printDeployment prints whether the certificates, PCR0 and signer of the attestation are
+// already known to the SystemConfigGlobal deployment and its CertManager.
+func printDeployment(ctx context.Context, rpcUrl string, address common.Address, res *nitrite.Result) error {
+	client, err := ethclient.DialContext(ctx, rpcUrl)
+	if err != nil {
+		return err
+	}
+	systemConfigGlobal, err := bindings.NewSystemConfigGlobal(address, client)
+	if err != nil {
+		return err
+	}
+	opts := &bind.CallOpts{Context: ctx}
+	certManagerAddr, err := systemConfigGlobal.CertManager(opts)
+	if err != nil {
+		return fmt.Errorf("failed to fetch CertManager address: %w", err)
+	}
+	certManager, err := bindings.NewCertManager(certManagerAddr, client)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Deployment:     SystemConfigGlobal %s, CertManager %s\n", address, certManagerAddr)
+	for i, der := range append(slices.Clone(res.Document.CABundle), res.Document.Certificate) {
+		hash := crypto.Keccak256Hash(der)
+		verified, err := certManager.Verified(opts, hash)
+		if err != nil {
+			return fmt.Errorf("failed to check certificate %s: %w", hash, err)
+		}
+		fmt.Printf("  Certificate %d: %s\n", i, known(len(verified) > 0, "verified", "not verified"))
+	}
+	validPCR0, err := systemConfigGlobal.ValidPCR0s(opts, crypto.Keccak256Hash(res.Document.PCRs[0]))
+	if err != nil {
+		return fmt.Errorf("failed to check PCR0: %w", err)
+	}
+	fmt.Printf("  PCR0:          %s\n", known(validPCR0, "registered", "not registered"))
+	if signer, ok := signerAddress(res.Document.PublicKey); ok {
+		validSigner, err := systemConfigGlobal.ValidSigners(opts, signer)
+		if err != nil {
+			return fmt.Errorf("failed to check signer: %w", err)
+		}
+		fmt.Printf("  Signer:        %s\n", known(validSigner, "registered", "not registered"))
+	}
+	return nil
+}
+
+// readAttestation decodes the attestation from a hex string, or from a file containing hex or binary.
+func readAttestation(input string) ([]byte, error) {
+	if data, err := os.ReadFile(input); err == nil {
+		if decoded, err := hexutil.Decode(strings.TrimSpace(string(data))); err == nil {
+			return decoded, nil
+		}
+		if decoded, err := hex.DecodeString(strings.TrimSpace(string(data))); err == nil {
+			return decoded, nil
+		}
+		return data, nil
+	}
+	decoded, err := hex.DecodeString(strings.TrimPrefix(strings.TrimSpace(input), "0x"))
+	if err != nil {
+		return nil, fmt.Errorf("attestation is neither a file nor hex: %w", err)
+	}
+	return decoded, nil
+}
+
+// signerAddress returns the address of an attested signer public key. Decryption attestations
+// contain an RSA public key instead, which isn't a signer.
+func signerAddress(publicKey []byte) (common.Address, bool) {
+	pub, err := crypto.UnmarshalPubkey(publicKey)
+	if err != nil {
+		return common.Address{}, false
+	}
+	return crypto.PubkeyToAddress(*pub), true
+}
+
+func isZero(b []byte) bool {
+	for _, v := range b {
+		if v != 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func known(ok bool, yes, no string) string {
+	if ok {
+		return yes
+	}
+	return no
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/ethereum/go-ethereum/rpc"
+)
+
+func main() {
+	var fromUrl string
+	var toUrl string
+	var trustedPCR0sPath string
+	var timeout time.Duration
+	flag.StringVar(&fromUrl, "from", "", "rpc url of the enclave that currently holds the signer key")
+	flag.StringVar(&toUrl, "to", "", "rpc url of the enclave to transfer the signer key to")
+	flag.StringVar(&trustedPCR0sPath, "trusted-pcr0s", "", "optional signed trusted PCR0s JSON file, passed to both enclaves before the transfer")
+	flag.DurationVar(&timeout, "timeout", time.Minute, "timeout for the transfer")
+	flag.Parse()
+
+	if fromUrl == "" || toUrl == "" {
+		flag.Usage()
+		os.Exit(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	from, err := rpc.DialContext(ctx, fromUrl)
+	if err != nil {
+		panic(err)
+	}
+	defer from.Close()
+	to, err := rpc.DialContext(ctx, toUrl)
+	if err != nil {
+		panic(err)
+	}
+	defer to.Close()
+
+	fromClient := &enclave.Client{Client: from}
+	toClient := &enclave.Client{Client: to}
+
+	if trustedPCR0sPath != "" {
+		data, err := os.ReadFile(trustedPCR0sPath)
+		if err != nil {
+			panic(err)
+		}
+		var trusted enclave.TrustedPCR0s
+		if err = json.Unmarshal(data, &trusted); err != nil {
+			panic(err)
+		}
+		if err = fromClient.SetTrustedPCR0s(ctx, &trusted); err != nil {
+			panic(err)
+		}
+		if err = toClient.SetTrustedPCR0s(ctx, &trusted); err != nil {
+			panic(err)
+		}
+		fmt.Printf("Set trusted PCR0s: %d PCR0s, expiring at %s\n", len(trusted.PCR0s), time.Unix(int64(trusted.Expiry), 0))
+	}
+
+	signer, err := enclave.TransferSignerKey(ctx, fromClient, toClient)
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("Transferred signer key: %s\n", signer.String())
+}
@@ -2,19 +2,24 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
 	"time"
 
 	"github.com/base/op-enclave/bindings"
-	"github.com/base/op-enclave/op-withdrawer/withdrawals"
+	"github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/base/op-enclave/tools/signers"
+	oplog "github.com/ethereum-optimism/optimism/op-service/log"
+	"github.com/ethereum-optimism/optimism/op-service/retry"
 	"github.com/ethereum/go-ethereum/accounts/abi/bind"
 	"github.com/ethereum/go-ethereum/common"
 	"github.com/ethereum/go-ethereum/common/hexutil"
-	"github.com/ethereum/go-ethereum/core/types"
-	"github.com/ethereum/go-ethereum/crypto"
 	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/hf/nitrite"
 )
 
@@ -23,145 +28,227 @@ func main() {
 	var rpcUrl string
 	var privateKeyHex string
 	var configAddress string
+	var rootsFile string
+	var verifyTime string
+	var dryRun bool
+	var outFile string
+	var safeBatchFile string
+	var stateFile string
+	var retries int
+	var enclaveRpc string
+	var minRemaining time.Duration
 	flag.StringVar(&attestationHex, "attestation", "", "attestation hex")
+	flag.StringVar(&enclaveRpc, "enclave-rpc", "", "op-enclave rpc url to fetch a fresh signer attestation from, instead of -attestation")
 	flag.StringVar(&rpcUrl, "rpc", "https://sepolia.base.org", "rpc url")
-	flag.StringVar(&privateKeyHex, "private-key", "", "private key")
+	flag.StringVar(&privateKeyHex, "private-key", "", "private key (not required with -dry-run)")
 	flag.StringVar(&configAddress, "address", "", "address of the SystemConfigGlobal proxy contract")
+	flag.StringVar(&rootsFile, "roots", "", "optional PEM file of root certificates to verify the attestation with (default: AWS Nitro root)")
+	flag.StringVar(&verifyTime, "verify-time", "", "optional time to verify the attestation certificates at, as RFC3339 or unix seconds (default: now)")
+	flag.BoolVar(&dryRun, "dry-run", false, "print the registration transactions instead of sending them")
+	flag.StringVar(&outFile, "out", "", "optional file to write the registration transactions to as JSON (implies -dry-run)")
+	flag.StringVar(&safeBatchFile, "safe-batch", "", "optional file to write a Safe Transaction Builder batch to, for execution by the SystemConfigGlobal owner Safe (implies -dry-run)")
+	flag.StringVar(&stateFile, "state-file", "", "optional file to record the progress of the registration in, to resume it after a failure")
+	flag.IntVar(&retries, "retries", 5, "number of attempts for each RPC call and transaction, with exponential backoff")
+	flag.DurationVar(&minRemaining, "min-remaining", 15*time.Minute, "abort if the attestation expires on chain sooner than this (only warns with -dry-run)")
 	flag.Parse()
 
-	if attestationHex == "" || privateKeyHex == "" || configAddress == "" {
+	dryRun = dryRun || outFile != "" || safeBatchFile != ""
+	if (attestationHex == "") == (enclaveRpc == "") || configAddress == "" || (privateKeyHex == "" && !dryRun) || retries < 1 {
 		flag.Usage()
 		os.Exit(1)
 	}
 
-	attestation, err := hexutil.Decode(attestationHex)
-	if err != nil {
-		panic(err)
-	}
-	privateKey, err := hexutil.Decode(privateKeyHex)
-	if err != nil {
-		panic(err)
+	oplog.SetupDefaults()
+	r := &registration{
+		rpcUrl:        rpcUrl,
+		privateKey:    privateKeyHex,
+		address:       common.HexToAddress(configAddress),
+		rootsFile:     rootsFile,
+		verifyTime:    verifyTime,
+		dryRun:        dryRun,
+		outFile:       outFile,
+		safeBatchFile: safeBatchFile,
+		stateFile:     stateFile,
+		retries:       retries,
+		enclaveRpc:    enclaveRpc,
+		minRemaining:  minRemaining,
+	}
+	if err := r.run(context.Background(), attestationHex); err != nil {
+		var stepErr *signers.StepError
+		if errors.As(err, &stepErr) {
+			fmt.Fprintf(os.Stderr, "Registration stopped at %s\n", err)
+			fmt.Fprintf(os.Stderr, "Earlier steps have landed and will be skipped; re-run the same command to resume.\n")
+		} else {
+			fmt.Fprintf(os.Stderr, "Registration failed: %s\n", err)
+		}
+		os.Exit(1)
 	}
+}
 
-	res, err := nitrite.Verify(attestation, nitrite.VerifyOptions{})
-	if err != nil {
-		panic(err)
-	}
+type registration struct {
+	rpcUrl        string
+	privateKey    string
+	address       common.Address
+	rootsFile     string
+	verifyTime    string
+	dryRun        bool
+	outFile       string
+	safeBatchFile string
+	stateFile     string
+	retries       int
+	enclaveRpc    string
+	minRemaining  time.Duration
+}
 
-	ctx := context.Background()
-	client, err := ethclient.DialContext(ctx, rpcUrl)
+func (r *registration) run(ctx context.Context, attestationHex string) error {
+	attestation, nonce, err := r.attestation(ctx, attestationHex)
 	if err != nil {
-		panic(err)
+		return err
 	}
 
-	key, err := crypto.ToECDSA(privateKey)
+	opts, err := signers.VerifyOptions(r.rootsFile, r.verifyTime)
 	if err != nil {
-		panic(err)
+		return err
 	}
-
-	chainId, err := client.ChainID(ctx)
+	// attestations fetched from the enclave must contain the nonce sent with the request, so that
+	// a replayed attestation isn't registered
+	opts.Nonce = nonce
+	res, err := enclave.VerifyAttestation(attestation, opts)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	signer := types.LatestSignerForChainID(chainId)
-	auth := &bind.TransactOpts{
-		From: crypto.PubkeyToAddress(key.PublicKey),
-		Signer: func(_ common.Address, tx *types.Transaction) (*types.Transaction, error) {
-			return types.SignTx(tx, signer, key)
-		},
-	}
-
-	systemConfigGlobal, err := bindings.NewSystemConfigGlobal(common.HexToAddress(configAddress), client)
+	signerAddr, err := signers.SignerAddress(res)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	fmt.Printf("Public key: %s\n", hexutil.Encode(res.Document.PublicKey))
 
-	pub, err := crypto.UnmarshalPubkey(res.Document.PublicKey)
+	client, err := ethclient.DialContext(ctx, r.rpcUrl)
 	if err != nil {
-		panic(err)
+		return fmt.Errorf("failed to dial rpc: %w", err)
 	}
-	signerAddr := crypto.PubkeyToAddress(*pub)
-	validSigner, err := systemConfigGlobal.ValidSigners(&bind.CallOpts{}, signerAddr)
+	defer client.Close()
+
+	strategy := retry.Exponential()
+	var registered bool
+	calls, err := retry.Do(ctx, r.retries, strategy, func() ([]*signers.Call, error) {
+		calls, err := signers.RegistrationCalls(ctx, client, r.address, res)
+		if errors.Is(err, signers.ErrAlreadyRegistered) {
+			registered = true
+			return nil, nil
+		}
+		return calls, err
+	})
 	if err != nil {
-		panic(err)
+		return err
 	}
-	fmt.Printf("Public key: %s\n", hexutil.Encode(res.Document.PublicKey))
-	if validSigner {
+	if registered {
 		fmt.Printf("Signer already registered: %s\n", signerAddr.String())
-		return
-	} else {
-		fmt.Printf("Registering signer: %s\n", signerAddr.String())
+		return nil
 	}
+	fmt.Printf("Registering signer: %s\n", signerAddr.String())
 
-	certManagerAddr, err := systemConfigGlobal.CertManager(&bind.CallOpts{})
+	if err = r.checkFreshness(ctx, client, res); err != nil {
+		return err
+	}
+	if r.dryRun {
+		return r.export(ctx, client, signerAddr, calls)
+	}
+
+	state, err := signers.LoadState(r.stateFile)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	certManager, err := bindings.NewCertManager(certManagerAddr, client)
+	txMgr, err := signers.NewTxManager(log.Root(), r.rpcUrl, r.privateKey)
 	if err != nil {
-		panic(err)
+		return err
 	}
+	defer txMgr.Close()
+	return signers.Send(ctx, txMgr, calls, state, r.retries)
+}
 
-	verifyCert := func(cert []byte, ca bool, parentCertHash common.Hash) common.Hash {
-		certHash := crypto.Keccak256Hash(cert)
-		verified, err := certManager.Verified(&bind.CallOpts{}, certHash)
+// attestation returns the attestation passed on the command line, or fetches a fresh one from the
+// enclave along with the random nonce that the attestation must contain.
+func (r *registration) attestation(ctx context.Context, attestationHex string) ([]byte, []byte, error) {
+	if r.enclaveRpc == "" {
+		attestation, err := hexutil.Decode(attestationHex)
 		if err != nil {
-			panic(err)
+			return nil, nil, fmt.Errorf("invalid attestation: %w", err)
 		}
-		if len(verified) == 0 {
-			var tx *types.Transaction
-			if ca {
-				tx, err = certManager.VerifyCACert(auth, cert, parentCertHash)
-			} else {
-				tx, err = certManager.VerifyClientCert(auth, cert, parentCertHash)
-			}
-			if err != nil {
-				panic(err)
-			}
-			receipt, err := withdrawals.WaitForReceipt(ctx, client, tx.Hash(), 2*time.Second)
-			if err != nil {
-				panic(err)
-			}
-			fmt.Printf("Verified cert: %s, tx: %s\n", certHash.String(), receipt.TxHash.String())
-		} else {
-			fmt.Printf("Cert already verified: %s\n", certHash.String())
-		}
-		return certHash
+		return attestation, nil, nil
 	}
-
-	parentCertHash := crypto.Keccak256Hash(res.Document.CABundle[0])
-	for i := 0; i < len(res.Document.CABundle); i++ {
-		cert := res.Document.CABundle[i]
-		parentCertHash = verifyCert(cert, true, parentCertHash)
+	nonce := make(hexutil.Bytes, 32)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	client, err := rpc.DialContext(ctx, r.enclaveRpc)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to dial enclave rpc: %w", err)
+	}
+	defer client.Close()
+	attestation, err := retry.Do(ctx, r.retries, retry.Exponential(), func() (hexutil.Bytes, error) {
+		return (&enclave.Client{Client: client}).SignerAttestation(ctx, &nonce, nil)
+	})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to fetch signer attestation: %w", err)
 	}
-	verifyCert(res.Document.Certificate, false, parentCertHash)
+	fmt.Printf("Fetched signer attestation from %s\n", r.enclaveRpc)
+	return attestation, nonce, nil
+}
 
-	pcr0Hash := crypto.Keccak256Hash(res.Document.PCRs[0])
-	valid, err := systemConfigGlobal.ValidPCR0s(&bind.CallOpts{}, pcr0Hash)
+// checkFreshness makes sure registerSigner can still be executed before the attestation exceeds
+// MAX_AGE, so that no gas is spent on certificate verification for a registration that would revert.
+func (r *registration) checkFreshness(ctx context.Context, client *ethclient.Client, res *nitrite.Result) error {
+	deadline, remaining, err := retry.Do2(ctx, r.retries, retry.Exponential(), func() (time.Time, time.Duration, error) {
+		return signers.AttestationDeadline(ctx, client, r.address, res)
+	})
 	if err != nil {
-		panic(err)
+		return err
 	}
-	if !valid {
-		tx, err := systemConfigGlobal.RegisterPCR0(auth, res.Document.PCRs[0])
-		if err != nil {
-			panic(err)
-		}
-		receipt, err := withdrawals.WaitForReceipt(ctx, client, tx.Hash(), 2*time.Second)
-		if err != nil {
-			panic(err)
+	fmt.Printf("The signer registration must be executed before %s, when the attestation expires (%s remaining)\n",
+		deadline.UTC().Format(time.RFC3339), remaining.Truncate(time.Second))
+	if remaining <= 0 {
+		return errors.New("attestation has expired, fetch a fresh one (e.g. with -enclave-rpc)")
+	}
+	if remaining < r.minRemaining {
+		if r.dryRun {
+			fmt.Printf("Warning: attestation expires in less than %s\n", r.minRemaining)
+			return nil
 		}
-		fmt.Printf("Registered PCR0, tx: %s\n", receipt.TxHash.String())
-	} else {
-		fmt.Printf("PCR0 already registered: %s\n", pcr0Hash.String())
+		return fmt.Errorf("attestation expires in less than %s, fetch a fresh one (e.g. with -enclave-rpc) or lower -min-remaining", r.minRemaining)
 	}
+	return nil
+}
 
-	tx, err := systemConfigGlobal.RegisterSigner(auth, res.COSESign1, res.Signature)
+func (r *registration) export(ctx context.Context, client *ethclient.Client, signerAddr common.Address, calls []*signers.Call) error {
+	opts := &bind.CallOpts{Context: ctx}
+	systemConfigGlobal, err := bindings.NewSystemConfigGlobal(r.address, client)
 	if err != nil {
-		panic(err)
+		return err
 	}
-	receipt, err := withdrawals.WaitForReceipt(ctx, client, tx.Hash(), 2*time.Second)
-	if err != nil {
-		panic(err)
+	signers.PrintCalls(calls)
+	if r.outFile != "" {
+		if err = signers.WriteCalls(r.outFile, calls); err != nil {
+			return fmt.Errorf("failed to write transactions: %w", err)
+		}
+		fmt.Printf("Wrote transactions to %s\n", r.outFile)
+	}
+	if r.safeBatchFile != "" {
+		chainId, err := client.ChainID(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to fetch chain id: %w", err)
+		}
+		owner, err := systemConfigGlobal.Owner(opts)
+		if err != nil {
+			return fmt.Errorf("failed to fetch owner: %w", err)
+		}
+		if err = signers.CheckSafe(client, owner); err != nil {
+			return err
+		}
+		if err = signers.WriteSafeBatch(r.safeBatchFile, chainId, owner, fmt.Sprintf("Register signer %s", signerAddr), calls); err != nil {
+			return fmt.Errorf("failed to write Safe batch: %w", err)
+		}
+		fmt.Printf("Wrote Safe transaction batch for %s to %s\n", owner, r.safeBatchFile)
 	}
-	fmt.Printf("Registered signer, tx: %s\n", receipt.TxHash.String())
+	return nil
 }
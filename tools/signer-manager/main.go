@@ -0,0 +1,475 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/base/op-enclave/bindings"
+	"github.com/base/op-enclave/tools/signers"
+	"github.com/ethereum-optimism/optimism/op-service/ctxinterrupt"
+	oplog "github.com/ethereum-optimism/optimism/op-service/log"
+	"github.com/ethereum-optimism/optimism/op-service/txmgr"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/hf/nitrite"
+	"github.com/urfave/cli/v2"
+)
+
+var (
+	RPCFlag = &cli.StringFlag{
+		Name:     "rpc",
+		Usage:    "URL of an L1 RPC host",
+		EnvVars:  []string{"L1_URL"},
+		Required: true,
+	}
+	AddressFlag = &cli.StringFlag{
+		Name:     "address",
+		Usage:    "Address of the SystemConfigGlobal proxy contract",
+		EnvVars:  []string{"SYSTEM_CONFIG_GLOBAL_ADDRESS"},
+		Required: true,
+	}
+	PrivateKeyFlag = &cli.StringFlag{
+		Name:    "private-key",
+		Usage:   "Private key of the SystemConfigGlobal owner or manager, to sign the transactions",
+		EnvVars: []string{"PRIVATE_KEY"},
+	}
+	SafeBatchFlag = &cli.StringFlag{
+		Name:  "safe-batch",
+		Usage: "Write a Safe Transaction Builder batch for the SystemConfigGlobal owner Safe to this file, instead of sending the transactions",
+	}
+	AttestationFlag = &cli.StringFlag{
+		Name:     "attestation",
+		Usage:    "Signer attestation hex (enclave_signerAttestation)",
+		Required: true,
+	}
+	RootsFlag = &cli.StringFlag{
+		Name:  "roots",
+		Usage: "PEM file of root certificates to verify the attestation with (default: AWS Nitro root)",
+	}
+	VerifyTimeFlag = &cli.StringFlag{
+		Name:  "verify-time",
+		Usage: "Time to verify the attestation certificates at, as RFC3339 or unix seconds (default: now)",
+	}
+	SignerFlag = &cli.StringFlag{
+		Name:     "signer",
+		Usage:    "Address of the signer to deregister",
+		Required: true,
+	}
+	MinRemainingFlag = &cli.DurationFlag{
+		Name:  "min-remaining",
+		Usage: "Abort if the attestation expires on chain sooner than this",
+		Value: 15 * time.Minute,
+	}
+	OldSignerFlag = &cli.StringFlag{
+		Name:     "old-signer",
+		Usage:    "Address of the signer to deregister once the new signer is in use",
+		Required: true,
+	}
+	OutputOracleFlag = &cli.StringFlag{
+		Name:     "output-oracle",
+		Usage:    "Address of the OutputOracle proxy contract the proposer submits to",
+		Required: true,
+	}
+	TimeoutFlag = &cli.DurationFlag{
+		Name:  "timeout",
+		Usage: "How long to wait for the proposer to submit an output signed by the new signer",
+		Value: time.Hour,
+	}
+	PollIntervalFlag = &cli.DurationFlag{
+		Name:  "poll-interval",
+		Usage: "How often to poll for new output proposals",
+		Value: 12 * time.Second,
+	}
+	FromBlockFlag = &cli.Uint64Flag{
+		Name:     "from-block",
+		Usage:    "L1 block to start scanning from, e.g. the SystemConfigGlobal deployment block",
+		Required: true,
+	}
+	ToBlockFlag = &cli.Uint64Flag{
+		Name:  "to-block",
+		Usage: "L1 block to stop scanning at (default: latest)",
+	}
+	StateFileFlag = &cli.StringFlag{
+		Name:  "state-file",
+		Usage: "File to record the progress of the transactions in, to resume after a failure",
+	}
+	RetriesFlag = &cli.IntFlag{
+		Name:  "retries",
+		Usage: "Number of attempts for each status check and transaction, with exponential backoff",
+		Value: 5,
+	}
+	ConcurrencyFlag = &cli.IntFlag{
+		Name:  "concurrency",
+		Usage: "Number of blocks to fetch concurrently",
+		Value: 8,
+	}
+)
+
+func main() {
+	oplog.SetupDefaults()
+
+	app := cli.NewApp()
+	app.Name = "signer-manager"
+	app.Usage = "Manages the enclave signers registered with SystemConfigGlobal"
+	app.Action = func(c *cli.Context) error {
+		return cli.ShowAppHelp(c)
+	}
+	app.Commands = []*cli.Command{
+		{
+			Name:   "list",
+			Usage:  "List the signer registration history and the currently registered signers",
+			Action: List,
+			Flags: []cli.Flag{
+				RPCFlag,
+				AddressFlag,
+				FromBlockFlag,
+				ToBlockFlag,
+				ConcurrencyFlag,
+			},
+		},
+		{
+			Name:   "register",
+			Usage:  "Register the signer of an enclave attestation",
+			Action: Register,
+			Flags: []cli.Flag{
+				RPCFlag,
+				AddressFlag,
+				AttestationFlag,
+				RootsFlag,
+				VerifyTimeFlag,
+				MinRemainingFlag,
+				PrivateKeyFlag,
+				SafeBatchFlag,
+				StateFileFlag,
+				RetriesFlag,
+			},
+		},
+		{
+			Name:   "deregister",
+			Usage:  "Deregister a signer",
+			Action: Deregister,
+			Flags: []cli.Flag{
+				RPCFlag,
+				AddressFlag,
+				SignerFlag,
+				PrivateKeyFlag,
+				SafeBatchFlag,
+				StateFileFlag,
+				RetriesFlag,
+			},
+		},
+		{
+			Name:   "rotate",
+			Usage:  "Register a new signer, wait until the proposer submits an output signed by it, then deregister the old signer",
+			Action: Rotate,
+			Flags: []cli.Flag{
+				RPCFlag,
+				AddressFlag,
+				AttestationFlag,
+				RootsFlag,
+				VerifyTimeFlag,
+				MinRemainingFlag,
+				OldSignerFlag,
+				OutputOracleFlag,
+				PrivateKeyFlag,
+				StateFileFlag,
+				RetriesFlag,
+				TimeoutFlag,
+				PollIntervalFlag,
+			},
+		},
+	}
+
+	ctx := ctxinterrupt.WithSignalWaiterMain(context.Background())
+	err := app.RunContext(ctx, os.Args)
+	if err != nil {
+		log.Crit("Application failed", "message", err)
+	}
+}
+
+func List(cliCtx *cli.Context) error {
+	ctx := cliCtx.Context
+	client, systemConfigGlobalAddr, err := dial(cliCtx)
+	if err != nil {
+		return err
+	}
+	systemConfigGlobal, err := bindings.NewSystemConfigGlobal(systemConfigGlobalAddr, client)
+	if err != nil {
+		return err
+	}
+
+	to := cliCtx.Uint64(ToBlockFlag.Name)
+	if to == 0 {
+		if to, err = client.BlockNumber(ctx); err != nil {
+			return err
+		}
+	}
+	events, err := signers.ScanHistory(ctx, client, systemConfigGlobalAddr, cliCtx.Uint64(FromBlockFlag.Name), to, cliCtx.Int(ConcurrencyFlag.Name))
+	if err != nil {
+		return err
+	}
+
+	var seen []common.Address
+	for _, event := range events {
+		if event.Registered {
+			fmt.Printf("Block %d: registered %s (PCR0 hash %s), tx: %s\n", event.BlockNumber, event.Signer, crypto.Keccak256Hash(event.PCR0), event.TxHash)
+		} else {
+			fmt.Printf("Block %d: deregistered %s, tx: %s\n", event.BlockNumber, event.Signer, event.TxHash)
+		}
+		if !contains(seen, event.Signer) {
+			seen = append(seen, event.Signer)
+		}
+	}
+
+	// the history only contains direct calls, so check the current state of each signer on chain
+	fmt.Println("Registered signers:")
+	for _, signer := range seen {
+		valid, err := systemConfigGlobal.ValidSigners(&bind.CallOpts{Context: ctx}, signer)
+		if err != nil {
+			return fmt.Errorf("failed to check signer %s: %w", signer, err)
+		}
+		if valid {
+			fmt.Printf("  %s\n", signer)
+		}
+	}
+	return nil
+}
+
+func Register(cliCtx *cli.Context) error {
+	ctx := cliCtx.Context
+	client, systemConfigGlobalAddr, err := dial(cliCtx)
+	if err != nil {
+		return err
+	}
+	res, err := verifyAttestation(cliCtx)
+	if err != nil {
+		return err
+	}
+	signer, err := signers.SignerAddress(res)
+	if err != nil {
+		return err
+	}
+	calls, err := signers.RegistrationCalls(ctx, client, systemConfigGlobalAddr, res)
+	if errors.Is(err, signers.ErrAlreadyRegistered) {
+		fmt.Printf("Signer already registered: %s\n", signer)
+		return nil
+	} else if err != nil {
+		return err
+	}
+	if err = checkFreshness(cliCtx, client, systemConfigGlobalAddr, res); err != nil {
+		return err
+	}
+	return execute(cliCtx, client, systemConfigGlobalAddr, fmt.Sprintf("Register signer %s", signer), calls)
+}
+
+func Deregister(cliCtx *cli.Context) error {
+	client, systemConfigGlobalAddr, err := dial(cliCtx)
+	if err != nil {
+		return err
+	}
+	signer, err := parseAddress(cliCtx.String(SignerFlag.Name))
+	if err != nil {
+		return err
+	}
+	systemConfigGlobal, err := bindings.NewSystemConfigGlobal(systemConfigGlobalAddr, client)
+	if err != nil {
+		return err
+	}
+	valid, err := systemConfigGlobal.ValidSigners(&bind.CallOpts{Context: cliCtx.Context}, signer)
+	if err != nil {
+		return fmt.Errorf("failed to check signer: %w", err)
+	}
+	if !valid {
+		fmt.Printf("Signer not registered: %s\n", signer)
+		return nil
+	}
+	call, err := signers.DeregistrationCall(client, systemConfigGlobalAddr, signer)
+	if err != nil {
+		return err
+	}
+	return execute(cliCtx, client, systemConfigGlobalAddr, call.Description, []*signers.Call{call})
+}
+
+func Rotate(cliCtx *cli.Context) error {
+	ctx := cliCtx.Context
+	client, systemConfigGlobalAddr, err := dial(cliCtx)
+	if err != nil {
+		return err
+	}
+	txMgr, state, err := sender(cliCtx)
+	if err != nil {
+		return err
+	}
+	defer txMgr.Close()
+	retries := cliCtx.Int(RetriesFlag.Name)
+	oldSigner, err := parseAddress(cliCtx.String(OldSignerFlag.Name))
+	if err != nil {
+		return err
+	}
+	outputOracleAddr, err := parseAddress(cliCtx.String(OutputOracleFlag.Name))
+	if err != nil {
+		return err
+	}
+	res, err := verifyAttestation(cliCtx)
+	if err != nil {
+		return err
+	}
+	newSigner, err := signers.SignerAddress(res)
+	if err != nil {
+		return err
+	}
+	if newSigner == oldSigner {
+		return fmt.Errorf("new signer is the same as the old signer: %s", newSigner)
+	}
+
+	// only proposals made after the new signer is registered can be signed by it
+	fromBlock, err := client.BlockNumber(ctx)
+	if err != nil {
+		return err
+	}
+	calls, err := signers.RegistrationCalls(ctx, client, systemConfigGlobalAddr, res)
+	if errors.Is(err, signers.ErrAlreadyRegistered) {
+		fmt.Printf("Signer already registered: %s\n", newSigner)
+	} else if err != nil {
+		return err
+	} else if err = checkFreshness(cliCtx, client, systemConfigGlobalAddr, res); err != nil {
+		return err
+	} else if err = signers.Send(ctx, txMgr, calls, state, retries); err != nil {
+		return err
+	}
+
+	fmt.Printf("Waiting for the proposer to submit an output signed by %s\n", newSigner)
+	waitCtx, cancel := context.WithTimeout(ctx, cliCtx.Duration(TimeoutFlag.Name))
+	defer cancel()
+	event, err := signers.WaitForProposalSigner(waitCtx, client, outputOracleAddr, newSigner, fromBlock, cliCtx.Duration(PollIntervalFlag.Name))
+	if err != nil {
+		return fmt.Errorf("new signer %s is registered, but the old signer %s was not deregistered: %w", newSigner, oldSigner, err)
+	}
+	fmt.Printf("Proposer is using the new signer, tx: %s\n", event.Raw.TxHash)
+
+	call, err := signers.DeregistrationCall(client, systemConfigGlobalAddr, oldSigner)
+	if err != nil {
+		return err
+	}
+	return signers.Send(ctx, txMgr, []*signers.Call{call}, state, retries)
+}
+
+// execute sends the calls, or writes them to a Safe batch file if requested.
+func execute(cliCtx *cli.Context, client *ethclient.Client, systemConfigGlobalAddr common.Address, name string, calls []*signers.Call) error {
+	ctx := cliCtx.Context
+	signers.PrintCalls(calls)
+	if file := cliCtx.String(SafeBatchFlag.Name); file != "" {
+		systemConfigGlobal, err := bindings.NewSystemConfigGlobal(systemConfigGlobalAddr, client)
+		if err != nil {
+			return err
+		}
+		owner, err := systemConfigGlobal.Owner(&bind.CallOpts{Context: ctx})
+		if err != nil {
+			return fmt.Errorf("failed to fetch owner: %w", err)
+		}
+		if err = signers.CheckSafe(client, owner); err != nil {
+			return err
+		}
+		chainId, err := client.ChainID(ctx)
+		if err != nil {
+			return err
+		}
+		if err = signers.WriteSafeBatch(file, chainId, owner, name, calls); err != nil {
+			return err
+		}
+		fmt.Printf("Wrote Safe transaction batch for %s to %s\n", owner, file)
+		return nil
+	}
+	txMgr, state, err := sender(cliCtx)
+	if err != nil {
+		return err
+	}
+	defer txMgr.Close()
+	return signers.Send(ctx, txMgr, calls, state, cliCtx.Int(RetriesFlag.Name))
+}
+
+// checkFreshness aborts if the attestation expires on chain sooner than --min-remaining, before
+// any gas is spent on certificate verification. Exporting a Safe batch only warns.
+func checkFreshness(cliCtx *cli.Context, client *ethclient.Client, systemConfigGlobalAddr common.Address, res *nitrite.Result) error {
+	deadline, remaining, err := signers.AttestationDeadline(cliCtx.Context, client, systemConfigGlobalAddr, res)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("The signer registration must be executed before %s, when the attestation expires (%s remaining)\n",
+		deadline.UTC().Format(time.RFC3339), remaining.Truncate(time.Second))
+	if remaining <= 0 {
+		return errors.New("attestation has expired, fetch a fresh one")
+	}
+	if minRemaining := cliCtx.Duration(MinRemainingFlag.Name); remaining < minRemaining {
+		if cliCtx.String(SafeBatchFlag.Name) != "" {
+			fmt.Printf("Warning: attestation expires in less than %s\n", minRemaining)
+			return nil
+		}
+		return fmt.Errorf("attestation expires in less than %s, fetch a fresh one or lower --%s", minRemaining, MinRemainingFlag.Name)
+	}
+	return nil
+}
+
+func dial(cliCtx *cli.Context) (*ethclient.Client, common.Address, error) {
+	address, err := parseAddress(cliCtx.String(AddressFlag.Name))
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	client, err := ethclient.DialContext(cliCtx.Context, cliCtx.String(RPCFlag.Name))
+	if err != nil {
+		return nil, common.Address{}, err
+	}
+	return client, address, nil
+}
+
+func verifyAttestation(cliCtx *cli.Context) (*nitrite.Result, error) {
+	attestation, err := hexutil.Decode(strings.TrimSpace(cliCtx.String(AttestationFlag.Name)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid attestation: %w", err)
+	}
+	return signers.VerifyAttestation(attestation, cliCtx.String(RootsFlag.Name), cliCtx.String(VerifyTimeFlag.Name))
+}
+
+// sender returns the transaction manager and the state to send calls with.
+func sender(cliCtx *cli.Context) (txmgr.TxManager, *signers.State, error) {
+	privateKey := cliCtx.String(PrivateKeyFlag.Name)
+	if privateKey == "" {
+		return nil, nil, fmt.Errorf("missing --%s", PrivateKeyFlag.Name)
+	}
+	var state *signers.State
+	if file := cliCtx.String(StateFileFlag.Name); file != "" {
+		var err error
+		if state, err = signers.LoadState(file); err != nil {
+			return nil, nil, err
+		}
+	}
+	txMgr, err := signers.NewTxManager(log.Root(), cliCtx.String(RPCFlag.Name), privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return txMgr, state, nil
+}
+
+func parseAddress(value string) (common.Address, error) {
+	if !common.IsHexAddress(value) {
+		return common.Address{}, fmt.Errorf("invalid address: %s", value)
+	}
+	return common.HexToAddress(value), nil
+}
+
+func contains(addresses []common.Address, address common.Address) bool {
+	for _, a := range addresses {
+		if a == address {
+			return true
+		}
+	}
+	return false
+}
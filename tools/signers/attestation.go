@@ -0,0 +1,46 @@
+package signers
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/base/op-enclave/op-enclave/enclave"
+	"github.com/hf/nitrite"
+)
+
+// VerifyOptions returns the options to verify attestations with, from the roots and verify time
+// command line options. An empty rootsFile uses the AWS Nitro root, and an empty verifyTime the
+// current time.
+func VerifyOptions(rootsFile string, verifyTime string) (enclave.VerifyOptions, error) {
+	opts := enclave.VerifyOptions{}
+	var err error
+	if rootsFile != "" {
+		if opts.Roots, err = enclave.LoadRoots(rootsFile); err != nil {
+			return enclave.VerifyOptions{}, err
+		}
+	}
+	if verifyTime != "" {
+		if opts.CurrentTime, err = ParseTime(verifyTime); err != nil {
+			return enclave.VerifyOptions{}, fmt.Errorf("invalid verify time: %w", err)
+		}
+	}
+	return opts, nil
+}
+
+// VerifyAttestation verifies an attestation with the roots and verify time command line options.
+func VerifyAttestation(attestation []byte, rootsFile string, verifyTime string) (*nitrite.Result, error) {
+	opts, err := VerifyOptions(rootsFile, verifyTime)
+	if err != nil {
+		return nil, err
+	}
+	return enclave.VerifyAttestation(attestation, opts)
+}
+
+// ParseTime parses a time given as RFC3339 or unix seconds.
+func ParseTime(value string) (time.Time, error) {
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
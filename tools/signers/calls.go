@@ -0,0 +1,127 @@
+// Package signers builds and sends the SystemConfigGlobal transactions that manage enclave signers.
+package signers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/base/op-enclave/bindings"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// Call is a signer management transaction, which is either sent directly or exported for a multisig.
+type Call struct {
+	Description string         `json:"description"`
+	To          common.Address `json:"to"`
+	Data        hexutil.Bytes  `json:"data"`
+	Value       *hexutil.Big   `json:"value"`
+
+	// done reports whether the call has already taken effect on chain, if known.
+	done func(ctx context.Context) (bool, error)
+}
+
+func newCall(metaData *bind.MetaData, to common.Address, description string, method string, args ...interface{}) (*Call, error) {
+	parsed, err := metaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	data, err := parsed.Pack(method, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to pack %s: %w", method, err)
+	}
+	return &Call{
+		Description: description,
+		To:          to,
+		Data:        data,
+		Value:       (*hexutil.Big)(new(big.Int)),
+	}, nil
+}
+
+// PrintCalls prints the calls in order, for review before they are sent or exported.
+func PrintCalls(calls []*Call) {
+	for i, c := range calls {
+		fmt.Printf("Transaction %d: %s\n  to:   %s\n  data: %s\n", i+1, c.Description, c.To, c.Data)
+	}
+}
+
+// WriteCalls writes the calls to a file as a JSON array of {description, to, data, value} objects.
+func WriteCalls(file string, calls []*Call) error {
+	data, err := json.MarshalIndent(calls, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
+
+// CheckSafe warns if the address doesn't look like a Safe, in which case it can't execute a batch.
+func CheckSafe(client *ethclient.Client, address common.Address) error {
+	safe, err := bindings.NewGnosisSafe(address, client)
+	if err != nil {
+		return err
+	}
+	threshold, err := safe.GetThreshold(&bind.CallOpts{})
+	if err != nil {
+		fmt.Printf("Warning: SystemConfigGlobal owner %s does not appear to be a Safe: %s\n", address, err)
+		return nil
+	}
+	owners, err := safe.GetOwners(&bind.CallOpts{})
+	if err != nil {
+		return fmt.Errorf("failed to fetch Safe owners: %w", err)
+	}
+	fmt.Printf("SystemConfigGlobal owner is a %d of %d Safe: %s\n", threshold, len(owners), address)
+	return nil
+}
+
+type safeBatch struct {
+	Version      string            `json:"version"`
+	ChainID      string            `json:"chainId"`
+	CreatedAt    int64             `json:"createdAt"`
+	Meta         safeBatchMeta     `json:"meta"`
+	Transactions []safeTransaction `json:"transactions"`
+}
+
+type safeBatchMeta struct {
+	Name                   string         `json:"name"`
+	Description            string         `json:"description"`
+	CreatedFromSafeAddress common.Address `json:"createdFromSafeAddress"`
+}
+
+type safeTransaction struct {
+	To    common.Address `json:"to"`
+	Value string         `json:"value"`
+	Data  hexutil.Bytes  `json:"data"`
+}
+
+// WriteSafeBatch writes the calls in the Safe Transaction Builder batch file format, which
+// can be imported into the Safe web app and executed as a single multisend transaction.
+func WriteSafeBatch(file string, chainId *big.Int, safe common.Address, name string, calls []*Call) error {
+	batch := safeBatch{
+		Version:   "1.0",
+		ChainID:   chainId.String(),
+		CreatedAt: time.Now().UnixMilli(),
+		Meta: safeBatchMeta{
+			Name:                   name,
+			CreatedFromSafeAddress: safe,
+		},
+	}
+	for _, c := range calls {
+		batch.Meta.Description += c.Description + "\n"
+		batch.Transactions = append(batch.Transactions, safeTransaction{
+			To:    c.To,
+			Value: c.Value.ToInt().String(),
+			Data:  c.Data,
+		})
+	}
+	data, err := json.MarshalIndent(batch, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0644)
+}
@@ -0,0 +1,140 @@
+package signers
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"sync"
+
+	"github.com/base/op-enclave/bindings"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/fxamacker/cbor/v2"
+	"github.com/hf/nitrite"
+	"golang.org/x/sync/errgroup"
+)
+
+// Event is a successful registerSigner or deregisterSigner transaction.
+type Event struct {
+	BlockNumber uint64
+	TxIndex     uint
+	TxHash      common.Hash
+	Signer      common.Address
+	Registered  bool
+	// PCR0 is the PCR0 of the attestation, for registrations.
+	PCR0 []byte
+}
+
+// ScanHistory scans the blocks in [from, to] for transactions that register or deregister signers.
+// SystemConfigGlobal doesn't emit events, so only transactions sent directly to the contract are
+// found; calls made through another contract (such as a Safe) are not.
+func ScanHistory(ctx context.Context, client *ethclient.Client, systemConfigGlobalAddr common.Address, from, to uint64, concurrency int) ([]*Event, error) {
+	parsed, err := bindings.SystemConfigGlobalMetaData.GetAbi()
+	if err != nil {
+		return nil, err
+	}
+	register := parsed.Methods["registerSigner"]
+	deregister := parsed.Methods["deregisterSigner"]
+
+	var mutex sync.Mutex
+	var events []*Event
+	g, ctx := errgroup.WithContext(ctx)
+	g.SetLimit(max(concurrency, 1))
+	for number := from; number <= to && ctx.Err() == nil; number++ {
+		g.Go(func() error {
+			block, err := client.BlockByNumber(ctx, new(big.Int).SetUint64(number))
+			if err != nil {
+				return fmt.Errorf("failed to fetch block %d: %w", number, err)
+			}
+			for i, tx := range block.Transactions() {
+				if tx.To() == nil || *tx.To() != systemConfigGlobalAddr || len(tx.Data()) < 4 {
+					continue
+				}
+				selector := tx.Data()[:4]
+				isRegister := bytes.Equal(selector, register.ID)
+				if !isRegister && !bytes.Equal(selector, deregister.ID) {
+					continue
+				}
+				receipt, err := client.TransactionReceipt(ctx, tx.Hash())
+				if err != nil {
+					return fmt.Errorf("failed to fetch receipt %s: %w", tx.Hash(), err)
+				}
+				if receipt.Status != types.ReceiptStatusSuccessful {
+					continue
+				}
+				event := &Event{
+					BlockNumber: number,
+					TxIndex:     uint(i),
+					TxHash:      tx.Hash(),
+					Registered:  isRegister,
+				}
+				if isRegister {
+					args, err := register.Inputs.Unpack(tx.Data()[4:])
+					if err != nil {
+						return fmt.Errorf("failed to unpack %s: %w", tx.Hash(), err)
+					}
+					doc, err := decodeAttestationTbs(args[0].([]byte))
+					if err != nil {
+						return fmt.Errorf("failed to decode attestation in %s: %w", tx.Hash(), err)
+					}
+					pub, err := crypto.UnmarshalPubkey(doc.PublicKey)
+					if err != nil {
+						return fmt.Errorf("failed to parse public key in %s: %w", tx.Hash(), err)
+					}
+					event.Signer = crypto.PubkeyToAddress(*pub)
+					event.PCR0 = doc.PCRs[0]
+				} else {
+					args, err := deregister.Inputs.Unpack(tx.Data()[4:])
+					if err != nil {
+						return fmt.Errorf("failed to unpack %s: %w", tx.Hash(), err)
+					}
+					event.Signer = args[0].(common.Address)
+				}
+				mutex.Lock()
+				events = append(events, event)
+				mutex.Unlock()
+			}
+			return nil
+		})
+	}
+	if err = g.Wait(); err != nil {
+		return nil, err
+	}
+	slices.SortFunc(events, func(a, b *Event) int {
+		if c := cmp.Compare(a.BlockNumber, b.BlockNumber); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.TxIndex, b.TxIndex)
+	})
+	return events, nil
+}
+
+// decodeAttestationTbs decodes the attestation document from the COSE_Sign1 signature structure
+// passed to registerSigner.
+func decodeAttestationTbs(tbs []byte) (*nitrite.Document, error) {
+	var sigStructure struct {
+		_ struct{} `cbor:",toarray"`
+
+		Context     string
+		Protected   []byte
+		ExternalAAD []byte
+		Payload     []byte
+	}
+	if err := cbor.Unmarshal(tbs, &sigStructure); err != nil {
+		return nil, err
+	}
+	doc := &nitrite.Document{}
+	if err := cbor.Unmarshal(sigStructure.Payload, doc); err != nil {
+		return nil, err
+	}
+	if len(doc.PCRs[0]) == 0 {
+		return nil, errors.New("attestation is missing PCR0")
+	}
+	return doc, nil
+}
@@ -0,0 +1,122 @@
+package signers
+
+import (
+	"context"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/base/op-enclave/bindings"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+)
+
+// ProposalSigner returns the signer of the output proposed in the OutputProposed event, by
+// recovering it from the proposeL2Output transaction in the same way the OutputOracle does.
+func ProposalSigner(ctx context.Context, client *ethclient.Client, outputOracle *bindings.OutputOracle, event *bindings.OutputOracleOutputProposed) (common.Address, error) {
+	parsed, err := bindings.OutputOracleMetaData.GetAbi()
+	if err != nil {
+		return common.Address{}, err
+	}
+	tx, _, err := client.TransactionByHash(ctx, event.Raw.TxHash)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to fetch proposal tx %s: %w", event.Raw.TxHash, err)
+	}
+	if len(tx.Data()) < 4 {
+		return common.Address{}, fmt.Errorf("proposal tx %s has no calldata", event.Raw.TxHash)
+	}
+	method, err := parsed.MethodById(tx.Data()[:4])
+	if err != nil || method.Name != "proposeL2Output" {
+		return common.Address{}, fmt.Errorf("proposal tx %s was not sent directly to the OutputOracle", event.Raw.TxHash)
+	}
+	args, err := method.Inputs.Unpack(tx.Data()[4:])
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to unpack proposal tx %s: %w", event.Raw.TxHash, err)
+	}
+	l2BlockNumber := args[1].(*big.Int)
+	l1BlockNumber := args[2].(*big.Int)
+	signature := common.CopyBytes(args[3].([]byte))
+
+	opts := &bind.CallOpts{Context: ctx, BlockNumber: new(big.Int).SetUint64(event.Raw.BlockNumber)}
+	configHash, err := outputOracle.ConfigHash(opts)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to fetch config hash: %w", err)
+	}
+	maxOutputCount, err := outputOracle.MaxOutputCount(opts)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to fetch max output count: %w", err)
+	}
+	prevIndex := new(big.Int).Add(event.L2OutputIndex, maxOutputCount)
+	prevIndex.Sub(prevIndex, common.Big1).Mod(prevIndex, maxOutputCount)
+	prevOutput, err := outputOracle.GetL2Output(opts, prevIndex)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to fetch previous output: %w", err)
+	}
+	l1Header, err := client.HeaderByNumber(ctx, l1BlockNumber)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to fetch L1 block %d: %w", l1BlockNumber, err)
+	}
+
+	hash := crypto.Keccak256(
+		configHash[:],
+		l1Header.Hash().Bytes(),
+		common.BigToHash(l2BlockNumber).Bytes(),
+		prevOutput.OutputRoot[:],
+		event.OutputRoot[:],
+	)
+	if len(signature) == crypto.SignatureLength && signature[64] >= 27 {
+		signature[64] -= 27
+	}
+	pub, err := crypto.SigToPub(hash, signature)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("failed to recover proposal signer: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// WaitForProposalSigner polls the OutputOracle for outputs proposed from the given L1 block onwards,
+// until an output signed by the given signer is proposed. Returns the OutputProposed event.
+func WaitForProposalSigner(ctx context.Context, client *ethclient.Client, outputOracleAddr common.Address, signer common.Address, fromBlock uint64, pollInterval time.Duration) (*bindings.OutputOracleOutputProposed, error) {
+	outputOracle, err := bindings.NewOutputOracle(outputOracleAddr, client)
+	if err != nil {
+		return nil, err
+	}
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+	for {
+		head, err := client.BlockNumber(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch block number: %w", err)
+		}
+		if head >= fromBlock {
+			iter, err := outputOracle.FilterOutputProposed(&bind.FilterOpts{Context: ctx, Start: fromBlock, End: &head}, nil, nil, nil)
+			if err != nil {
+				return nil, fmt.Errorf("failed to filter proposals: %w", err)
+			}
+			for iter.Next() {
+				proposalSigner, err := ProposalSigner(ctx, client, outputOracle, iter.Event)
+				if err != nil {
+					_ = iter.Close()
+					return nil, err
+				}
+				fmt.Printf("Output proposed for L2 block %s by signer %s\n", iter.Event.L2BlockNumber, proposalSigner)
+				if proposalSigner == signer {
+					_ = iter.Close()
+					return iter.Event, nil
+				}
+			}
+			if err = iter.Error(); err != nil {
+				return nil, fmt.Errorf("failed to filter proposals: %w", err)
+			}
+			_ = iter.Close()
+			fromBlock = head + 1
+		}
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
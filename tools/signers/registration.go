@@ -0,0 +1,159 @@
+package signers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/base/op-enclave/bindings"
+	"github.com/ethereum/go-ethereum/accounts/abi/bind"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/ethclient"
+	"github.com/hf/nitrite"
+)
+
+var ErrAlreadyRegistered = errors.New("signer already registered")
+
+// SignerAddress returns the address of an attested signer public key.
+func SignerAddress(res *nitrite.Result) (common.Address, error) {
+	pub, err := crypto.UnmarshalPubkey(res.Document.PublicKey)
+	if err != nil {
+		return common.Address{}, fmt.Errorf("attestation does not contain a signer public key: %w", err)
+	}
+	return crypto.PubkeyToAddress(*pub), nil
+}
+
+// RegistrationCalls returns the transactions that register the signer of a verified attestation
+// with SystemConfigGlobal, in order, skipping the certificates and PCR0 that are already registered.
+// Returns ErrAlreadyRegistered if the signer is already registered.
+func RegistrationCalls(ctx context.Context, client *ethclient.Client, systemConfigGlobalAddr common.Address, res *nitrite.Result) ([]*Call, error) {
+	opts := &bind.CallOpts{Context: ctx}
+	systemConfigGlobal, err := bindings.NewSystemConfigGlobal(systemConfigGlobalAddr, client)
+	if err != nil {
+		return nil, err
+	}
+
+	signerAddr, err := SignerAddress(res)
+	if err != nil {
+		return nil, err
+	}
+	validSigner, err := systemConfigGlobal.ValidSigners(opts, signerAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check signer: %w", err)
+	}
+	if validSigner {
+		return nil, fmt.Errorf("%w: %s", ErrAlreadyRegistered, signerAddr)
+	}
+
+	certManagerAddr, err := systemConfigGlobal.CertManager(opts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch CertManager address: %w", err)
+	}
+	certManager, err := bindings.NewCertManager(certManagerAddr, client)
+	if err != nil {
+		return nil, err
+	}
+
+	var calls []*Call
+	verifyCert := func(cert []byte, ca bool, parentCertHash common.Hash) (common.Hash, error) {
+		certHash := crypto.Keccak256Hash(cert)
+		verified, err := certManager.Verified(opts, certHash)
+		if err != nil {
+			return common.Hash{}, fmt.Errorf("failed to check cert %s: %w", certHash, err)
+		}
+		if len(verified) > 0 {
+			fmt.Printf("Cert already verified: %s\n", certHash.String())
+			return certHash, nil
+		}
+		method, description := "verifyClientCert", "Verify client cert"
+		if ca {
+			method, description = "verifyCACert", "Verify CA cert"
+		}
+		c, err := newCall(bindings.CertManagerMetaData, certManagerAddr, fmt.Sprintf("%s %s", description, certHash), method, cert, parentCertHash)
+		if err != nil {
+			return common.Hash{}, err
+		}
+		c.done = func(ctx context.Context) (bool, error) {
+			verified, err := certManager.Verified(&bind.CallOpts{Context: ctx}, certHash)
+			return len(verified) > 0, err
+		}
+		calls = append(calls, c)
+		return certHash, nil
+	}
+
+	parentCertHash := crypto.Keccak256Hash(res.Document.CABundle[0])
+	for _, cert := range res.Document.CABundle {
+		if parentCertHash, err = verifyCert(cert, true, parentCertHash); err != nil {
+			return nil, err
+		}
+	}
+	if _, err = verifyCert(res.Document.Certificate, false, parentCertHash); err != nil {
+		return nil, err
+	}
+
+	pcr0Hash := crypto.Keccak256Hash(res.Document.PCRs[0])
+	validPCR0, err := systemConfigGlobal.ValidPCR0s(opts, pcr0Hash)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check PCR0: %w", err)
+	}
+	if validPCR0 {
+		fmt.Printf("PCR0 already registered: %s\n", pcr0Hash.String())
+	} else {
+		c, err := newCall(bindings.SystemConfigGlobalMetaData, systemConfigGlobalAddr, fmt.Sprintf("Register PCR0 %s", pcr0Hash), "registerPCR0", res.Document.PCRs[0])
+		if err != nil {
+			return nil, err
+		}
+		c.done = func(ctx context.Context) (bool, error) {
+			return systemConfigGlobal.ValidPCR0s(&bind.CallOpts{Context: ctx}, pcr0Hash)
+		}
+		calls = append(calls, c)
+	}
+
+	c, err := newCall(bindings.SystemConfigGlobalMetaData, systemConfigGlobalAddr, fmt.Sprintf("Register signer %s", signerAddr), "registerSigner", res.COSESign1, res.Signature)
+	if err != nil {
+		return nil, err
+	}
+	c.done = func(ctx context.Context) (bool, error) {
+		return systemConfigGlobal.ValidSigners(&bind.CallOpts{Context: ctx}, signerAddr)
+	}
+	return append(calls, c), nil
+}
+
+// AttestationDeadline returns the time after which registerSigner rejects the attestation, which
+// is MAX_AGE after the attestation's timestamp, and the time remaining until then as of the latest
+// L1 block (the contract compares against the block timestamp, not the local clock).
+func AttestationDeadline(ctx context.Context, client *ethclient.Client, systemConfigGlobalAddr common.Address, res *nitrite.Result) (time.Time, time.Duration, error) {
+	systemConfigGlobal, err := bindings.NewSystemConfigGlobal(systemConfigGlobalAddr, client)
+	if err != nil {
+		return time.Time{}, 0, err
+	}
+	maxAge, err := systemConfigGlobal.MAXAGE(&bind.CallOpts{Context: ctx})
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("failed to fetch MAX_AGE: %w", err)
+	}
+	header, err := client.HeaderByNumber(ctx, nil)
+	if err != nil {
+		return time.Time{}, 0, fmt.Errorf("failed to fetch latest header: %w", err)
+	}
+	deadline := time.UnixMilli(int64(res.Document.Timestamp)).Add(time.Duration(maxAge.Int64()) * time.Second)
+	return deadline, deadline.Sub(time.Unix(int64(header.Time), 0)), nil
+}
+
+// DeregistrationCall returns the transaction that deregisters a signer from SystemConfigGlobal.
+func DeregistrationCall(client *ethclient.Client, systemConfigGlobalAddr common.Address, signer common.Address) (*Call, error) {
+	systemConfigGlobal, err := bindings.NewSystemConfigGlobal(systemConfigGlobalAddr, client)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newCall(bindings.SystemConfigGlobalMetaData, systemConfigGlobalAddr, fmt.Sprintf("Deregister signer %s", signer), "deregisterSigner", signer)
+	if err != nil {
+		return nil, err
+	}
+	c.done = func(ctx context.Context) (bool, error) {
+		valid, err := systemConfigGlobal.ValidSigners(&bind.CallOpts{Context: ctx}, signer)
+		return !valid, err
+	}
+	return c, nil
+}
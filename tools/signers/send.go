@@ -0,0 +1,179 @@
+package signers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ethereum-optimism/optimism/op-service/retry"
+	"github.com/ethereum-optimism/optimism/op-service/txmgr"
+	"github.com/ethereum-optimism/optimism/op-service/txmgr/metrics"
+	"github.com/ethereum/go-ethereum/common"
+	"github.com/ethereum/go-ethereum/core/types"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+)
+
+// StepError is returned by Send when a call fails, and identifies the step that failed.
+// Steps before it have landed, and are skipped when the calls are sent again.
+type StepError struct {
+	Step        int
+	Total       int
+	Description string
+	Err         error
+}
+
+func (e *StepError) Error() string {
+	return fmt.Sprintf("step %d of %d (%s) failed: %s", e.Step, e.Total, e.Description, e.Err)
+}
+
+func (e *StepError) Unwrap() error {
+	return e.Err
+}
+
+// State is the progress of a sequence of calls, persisted to a file after every step so that
+// an interrupted run can be inspected and resumed.
+type State struct {
+	Steps []*StepState `json:"steps"`
+
+	file string
+}
+
+// StepState is the progress of a single call.
+type StepState struct {
+	Description string         `json:"description"`
+	To          common.Address `json:"to"`
+	DataHash    common.Hash    `json:"dataHash"`
+	Done        bool           `json:"done"`
+	TxHash      *common.Hash   `json:"txHash,omitempty"`
+	BlockNumber uint64         `json:"blockNumber,omitempty"`
+}
+
+// LoadState loads the state file, or returns an empty state if it doesn't exist yet.
+func LoadState(file string) (*State, error) {
+	s := &State{file: file}
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("failed to read state file: %w", err)
+	}
+	if err = json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse state file %s: %w", file, err)
+	}
+	return s, nil
+}
+
+func (s *State) step(c *Call) *StepState {
+	dataHash := crypto.Keccak256Hash(c.Data)
+	if s == nil {
+		return &StepState{Description: c.Description, To: c.To, DataHash: dataHash}
+	}
+	for _, step := range s.Steps {
+		if step.To == c.To && step.DataHash == dataHash {
+			return step
+		}
+	}
+	step := &StepState{Description: c.Description, To: c.To, DataHash: dataHash}
+	s.Steps = append(s.Steps, step)
+	return step
+}
+
+func (s *State) save() error {
+	if s == nil || s.file == "" {
+		return nil
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	// write to a temporary file first, so an interrupted write doesn't lose the previous state
+	tmp := s.file + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	if err = os.Rename(tmp, s.file); err != nil {
+		return fmt.Errorf("failed to write state file: %w", err)
+	}
+	return nil
+}
+
+// NewTxManager returns a transaction manager that sends transactions from the given private key,
+// and handles nonces, fee estimation and fee bumping.
+func NewTxManager(logger log.Logger, rpcUrl string, privateKey string) (txmgr.TxManager, error) {
+	cfg := txmgr.NewCLIConfig(rpcUrl, txmgr.DefaultChallengerFlagValues)
+	cfg.PrivateKey = privateKey
+	m, err := txmgr.NewSimpleTxManager("signers", logger, &metrics.NoopTxMetrics{}, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create tx manager: %w", err)
+	}
+	return m, nil
+}
+
+// Send sends the calls in order, waiting for each to be confirmed. Calls that have already taken
+// effect on chain, or that are recorded as done in the state, are skipped, so Send can be re-run
+// after a failure. Checks and sends are attempted up to attempts times with exponential backoff.
+// The state may be nil. A failed call is returned as a *StepError.
+func Send(ctx context.Context, txMgr txmgr.TxManager, calls []*Call, state *State, attempts int) error {
+	strategy := retry.Exponential()
+	for i, c := range calls {
+		step := state.step(c)
+		fail := func(err error) error {
+			return &StepError{Step: i + 1, Total: len(calls), Description: c.Description, Err: err}
+		}
+
+		done, err := retry.Do(ctx, attempts, strategy, func() (bool, error) {
+			return c.isDone(ctx, step)
+		})
+		if err != nil {
+			return fail(fmt.Errorf("failed to check status: %w", err))
+		}
+		if done {
+			fmt.Printf("Already done: %s\n", c.Description)
+		} else {
+			receipt, err := retry.Do(ctx, attempts, strategy, func() (*types.Receipt, error) {
+				// a previous attempt may have landed before it failed
+				if done, err := c.isDone(ctx, step); err != nil || done {
+					return nil, err
+				}
+				return txMgr.Send(ctx, txmgr.TxCandidate{
+					TxData: c.Data,
+					To:     &c.To,
+					Value:  c.Value.ToInt(),
+				})
+			})
+			if err != nil {
+				return fail(err)
+			}
+			if receipt != nil {
+				step.TxHash = &receipt.TxHash
+				step.BlockNumber = receipt.BlockNumber.Uint64()
+				if receipt.Status != types.ReceiptStatusSuccessful {
+					if err = state.save(); err != nil {
+						return err
+					}
+					return fail(fmt.Errorf("transaction reverted, tx: %s", receipt.TxHash))
+				}
+				fmt.Printf("%s, tx: %s\n", c.Description, receipt.TxHash)
+			} else {
+				fmt.Printf("Already done: %s\n", c.Description)
+			}
+		}
+
+		step.Done = true
+		if err = state.save(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isDone checks the chain if the call knows how to, and falls back to the recorded state otherwise.
+func (c *Call) isDone(ctx context.Context, step *StepState) (bool, error) {
+	if c.done == nil {
+		return step.Done, nil
+	}
+	return c.done(ctx)
+}